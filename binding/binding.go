@@ -4,6 +4,7 @@ package binding
 
 import (
 	"encoding/json"
+	"github.com/codegangsta/inject"
 	"github.com/codegangsta/martini"
 	"net/http"
 	"reflect"
@@ -111,9 +112,13 @@ func MultipartForm(formStruct interface{}, ifacePtr ...interface{}) martini.Hand
 // Json is middleware to deserialize a JSON payload from the request
 // into the struct that is passed in. The resulting struct is then
 // validated, but no error handling is actually performed here.
+// A slice of structs (or of pointers to structs) may be passed in to
+// bind a JSON array, in which case every element is validated and its
+// errors are keyed by index. Any other slice panics.
 // An interface pointer can be added as a second argument in order
 // to map the struct to a specific interface.
 func Json(jsonStruct interface{}, ifacePtr ...interface{}) martini.Handler {
+	ensureSliceOfStructsOrPointers(jsonStruct)
 	return func(context martini.Context, req *http.Request) {
 		ensureNotPointer(jsonStruct)
 		jsonStruct := reflect.New(reflect.TypeOf(jsonStruct))
@@ -133,19 +138,49 @@ func Json(jsonStruct interface{}, ifacePtr ...interface{}) martini.Handler {
 
 // Validate is middleware to enforce required fields. If the struct
 // passed in is a Validator, then the user-defined Validate method
-// is executed, and its errors are mapped to the context. This middleware
+// is executed, and its errors are mapped to the context. If the struct
+// is a ServiceValidator, the handler returned by its ValidationHandler
+// method is then invoked through the Martini injector. A slice of
+// structs is validated element by element, with the errors of each
+// element keyed by its index, like "[2].Title". This middleware
 // performs no error handling: it merely detects them and maps them.
 func Validate(obj interface{}) martini.Handler {
 	return func(context martini.Context, req *http.Request) {
-		errors := newErrors()
-		validateStruct(errors, obj)
+		context.Map(*validate(context, req, obj))
+	}
+}
 
-		if validator, ok := obj.(Validator); ok {
-			validator.Validate(errors, req)
+func validate(context martini.Context, req *http.Request, obj interface{}) *Errors {
+	errors := newErrors()
+
+	if val := reflect.Indirect(reflect.ValueOf(obj)); val.Kind() == reflect.Slice {
+		for i := 0; i < val.Len(); i++ {
+			elem := val.Index(i)
+			if elem.Kind() != reflect.Ptr {
+				elem = elem.Addr()
+			} else if elem.IsNil() {
+				continue
+			}
+			errors.combineElement(i, *validate(context, req, elem.Interface()))
 		}
-		context.Map(*errors)
+	} else {
+		validateStruct(errors, obj)
+	}
 
+	if validator, ok := obj.(Validator); ok {
+		validator.Validate(errors, req)
+	}
+	if validator, ok := obj.(ServiceValidator); ok {
+		// A child injector keeps *Errors out of the request context,
+		// where it would go stale once the errors are combined
+		injector := inject.New()
+		injector.SetParent(context)
+		injector.Map(errors)
+		if _, err := injector.Invoke(validator.ValidationHandler()); err != nil {
+			panic(err)
+		}
 	}
+	return errors
 }
 
 func validateStruct(errors *Errors, obj interface{}) {
@@ -180,6 +215,10 @@ func validateStruct(errors *Errors, obj interface{}) {
 
 func mapForm(formStruct reflect.Value, form map[string][]string, errors *Errors) {
 	typ := formStruct.Elem().Type()
+	if typ.Kind() != reflect.Struct {
+		errors.Overall[DeserializationError] = "form data can only be bound to a struct"
+		return
+	}
 
 	for i := 0; i < typ.NumField(); i++ {
 		typeField := typ.Field(i)
@@ -293,6 +332,18 @@ func ensureNotPointer(obj interface{}) {
 	}
 }
 
+// Slices are validated element by element, so their elements
+// must be structs or pointers to structs.
+func ensureSliceOfStructsOrPointers(obj interface{}) {
+	typ := reflect.TypeOf(obj)
+	if typ.Kind() != reflect.Slice {
+		return
+	}
+	if elem := typ.Elem(); elem.Kind() != reflect.Struct && (elem.Kind() != reflect.Ptr || elem.Elem().Kind() != reflect.Struct) {
+		panic("Json binding models must be a struct or a slice of structs")
+	}
+}
+
 // Performs validation and combines errors from validation
 // with errors from deserialization, then maps both the
 // resulting struct and the errors to the context.
@@ -327,6 +378,18 @@ func (this *Errors) combine(other Errors) {
 	}
 }
 
+// combineElement adds the errors of the slice element at index,
+// prefixing each key with the index, like "[2].Title".
+func (this *Errors) combineElement(index int, other Errors) {
+	prefix := "[" + strconv.Itoa(index) + "]."
+	for key, val := range other.Fields {
+		this.Fields[prefix+key] = val
+	}
+	for key, val := range other.Overall {
+		this.Overall[prefix+key] = val
+	}
+}
+
 // Total errors is the sum of errors with the request overall
 // and errors on individual fields.
 func (self Errors) Count() int {
@@ -347,12 +410,25 @@ type (
 	Validator interface {
 		Validate(*Errors, *http.Request)
 	}
+
+	// Implement the ServiceValidator interface when your validation
+	// needs services mapped into the Martini context, such as a database
+	// handle or a session. The returned handler is invoked through the
+	// injector after the struct tags and any Validator have been checked,
+	// so it may ask for *Errors, *http.Request and any mapped service.
+	ServiceValidator interface {
+		ValidationHandler() martini.Handler
+	}
 )
 
 var (
 	// Maximum amount of memory to use when parsing a multipart form.
 	// Set this to whatever value you prefer; default is 10 MB.
 	MaxMemory = int64(1024 * 1024 * 10)
+
+	// Maximum length of a single line read by the NDJson middleware.
+	// Longer lines stop the stream with an error; default is 1 MB.
+	MaxLineSize = 1024 * 1024
 )
 
 const (
@@ -361,4 +437,6 @@ const (
 	IntegerTypeError     string = "IntegerTypeError"
 	BooleanTypeError     string = "BooleanTypeError"
 	FloatTypeError       string = "FloatTypeError"
+	UnknownFieldError    string = "UnknownFieldError"
+	RangeError           string = "RangeError"
 )
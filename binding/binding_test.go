@@ -5,6 +5,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -89,6 +90,95 @@ func TestValidate(t *testing.T) {
 	performValidationTest(&User{Name: "Jim", Home: Address{"required", ""}}, handlerNoErr, t)
 }
 
+func TestJsonSlice(t *testing.T) {
+	payload := `[
+		{"title":"Blog Post Title", "content":"This is the content"},
+		{"title":"", "content":"This is the content"},
+		{"title":"Tiny", "content":"..."}
+	]`
+
+	m := martini.Classic()
+	m.Post(route, Json([]BlogPost{}), func(posts []BlogPost, errors Errors) {
+		if len(posts) != 3 || posts[0].Title != "Blog Post Title" {
+			t.Errorf("Expected three posts to be bound, got %+v", posts)
+		}
+		if _, ok := errors.Fields["[0].Title"]; ok {
+			t.Errorf("Did not expect errors on element 0, got %+v", errors)
+		}
+		if _, ok := errors.Fields["[1].Title"]; !ok {
+			t.Errorf("Expected element 1 to require a title, got %+v", errors)
+		}
+		if errors.Fields["[2].Content"] != "Too short; minimum 5 characters" {
+			t.Errorf("Expected element 2 to fail its Validator, got %+v", errors)
+		}
+	})
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", route, strings.NewReader(payload))
+	if err != nil {
+		t.Error(err)
+	}
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the handler to run, got status code %d", recorder.Code)
+	}
+}
+
+func TestJsonSliceOfNonStructs(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Json to reject a slice of strings")
+		}
+	}()
+	Json([]string{})
+}
+
+func TestServiceValidator(t *testing.T) {
+	taken := usernames{"jim": true}
+
+	for name, expectErr := range map[string]bool{"jim": true, "bob": false} {
+		recorder := httptest.NewRecorder()
+		m := martini.Classic()
+		m.Map(taken)
+		m.Get(route, Validate(&Account{Username: name}), func(errors Errors) {
+			if expectErr && errors.Fields["username"] != "Username is taken" {
+				t.Errorf("Expected username %q to be rejected, got %+v", name, errors)
+			} else if !expectErr && errors.Count() > 0 {
+				t.Errorf("Expected username %q to be accepted, got %+v", name, errors)
+			}
+		})
+
+		req, err := http.NewRequest("GET", route, nil)
+		if err != nil {
+			t.Error("HTTP error:", err)
+		}
+
+		m.ServeHTTP(recorder, req)
+	}
+}
+
+func TestServiceValidatorErrorsNotMapped(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	m := martini.Classic()
+	m.Map(usernames{"jim": true})
+	m.Get(route, Validate(&Account{Username: "jim"}), func(context martini.Context, errors Errors) {
+		if errors.Fields["username"] != "Username is taken" {
+			t.Errorf("Expected the validation handler errors to be mapped, got %+v", errors)
+		}
+		if context.Get(reflect.TypeOf(&Errors{})).IsValid() {
+			t.Error("Expected *Errors of the validation handler not to stay mapped in the request context")
+		}
+	})
+
+	req, err := http.NewRequest("GET", route, nil)
+	if err != nil {
+		t.Error("HTTP error:", err)
+	}
+
+	m.ServeHTTP(recorder, req)
+}
+
 func handle(test testCase, t *testing.T, index int, post BlogPost, errors Errors) {
 	assertEqualField(t, "Title", index, test.ref.Title, post.Title)
 	assertEqualField(t, "Content", index, test.ref.Content, post.Content)
@@ -294,6 +384,14 @@ func (self BlogPost) Create(test testCase, t *testing.T, index int) {
 	}
 }
 
+func (self Account) ValidationHandler() martini.Handler {
+	return func(errors *Errors, taken usernames) {
+		if taken[self.Username] {
+			errors.Fields["username"] = "Username is taken"
+		}
+	}
+}
+
 func (self BlogSection) Create(test testCase, t *testing.T, index int) {
 	// intentionally left empty
 }
@@ -334,6 +432,12 @@ type (
 		Street1 string `json:"street1" binding:"required"`
 		Street2 string `json:"street2"`
 	}
+
+	Account struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	usernames map[string]bool
 )
 
 var (
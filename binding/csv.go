@@ -0,0 +1,169 @@
+package binding
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/codegangsta/martini"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Csv is middleware to deserialize CSV data from the request into
+// the slice of structs that is passed in, one element per row. The
+// first row is the header, and each column is mapped to the field
+// whose csv tag matches its name, with the same type conversion as
+// the Form middleware. The data may be the request body itself or a
+// file uploaded in a multipart form under the CsvField name. Every
+// row is then validated, and errors are keyed by row index (not
+// counting the header) and column, like "[2].views". A row with
+// too few or too many fields gets a DeserializationError of its
+// own, like "[2].DeserializationError", and the rest of the data
+// is still read. No error handling is performed here.
+// An interface pointer can be added as a second argument in order
+// to map the slice to a specific interface.
+func Csv(csvSlice interface{}, ifacePtr ...interface{}) martini.Handler {
+	ensureNotPointer(csvSlice)
+	ensureSliceOfStructs(csvSlice)
+
+	return func(context martini.Context, req *http.Request) {
+		csvSlice := reflect.New(reflect.TypeOf(csvSlice))
+		errors := newErrors()
+
+		body, err := csvBody(req)
+		if err != nil {
+			errors.Overall[DeserializationError] = err.Error()
+		} else {
+			defer body.Close()
+			reader := csv.NewReader(body)
+			reader.FieldsPerRecord = -1
+			mapCsv(csvSlice, reader, errors)
+		}
+
+		// Validation errors are keyed by column too, rather than by field
+		validation := validate(context, req, csvSlice.Interface())
+		errors.combine(csvColumnErrors(*validation, csvSlice.Elem().Type().Elem()))
+		context.Map(*errors)
+		context.Map(csvSlice.Elem().Interface())
+		if len(ifacePtr) > 0 {
+			context.MapTo(csvSlice.Elem().Interface(), ifacePtr[0])
+		}
+	}
+}
+
+// csvBody returns the uploaded file of a multipart form,
+// or the request body otherwise.
+func csvBody(req *http.Request) (io.ReadCloser, error) {
+	if strings.Contains(req.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := req.ParseMultipartForm(MaxMemory); err != nil {
+			return nil, err
+		}
+
+		files := req.MultipartForm.File[CsvField]
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no file uploaded in the %q field", CsvField)
+		}
+		return files[0].Open()
+	}
+
+	if req.Body == nil {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return req.Body, nil
+}
+
+func mapCsv(csvSlice reflect.Value, reader *csv.Reader, errors *Errors) {
+	header, err := reader.Read()
+	if err == io.EOF {
+		return
+	} else if err != nil {
+		errors.Overall[DeserializationError] = err.Error()
+		return
+	}
+
+	// Spreadsheet exports often start with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	slice := csvSlice.Elem()
+	typ := slice.Type().Elem()
+
+	// The index of the struct field for each column, or -1
+	columns := make([]int, len(header))
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+		columns[i] = -1
+		for j := 0; j < typ.NumField(); j++ {
+			if name := typ.Field(j).Tag.Get("csv"); name != "" && name == header[i] {
+				columns[i] = j
+				break
+			}
+		}
+	}
+
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			errors.Overall[DeserializationError] = err.Error()
+			break
+		}
+
+		elem := reflect.New(typ).Elem()
+		rowErrors := newErrors()
+		if len(record) != len(header) {
+			rowErrors.Overall[DeserializationError] = fmt.Sprintf("row has %d fields, expected %d", len(record), len(header))
+		}
+		for i, value := range record {
+			if i >= len(columns) || columns[i] < 0 {
+				continue
+			}
+			if structField := elem.Field(columns[i]); structField.CanSet() {
+				setWithProperType(structField.Kind(), value, structField, header[i], rowErrors)
+			}
+		}
+
+		errors.combineElement(row, *rowErrors)
+		slice.Set(reflect.Append(slice, elem))
+	}
+}
+
+// csvColumnErrors returns the errors of a slice of rows with the
+// struct field of each key replaced by its column, so "[2].Title"
+// becomes "[2].title". Other keys are left alone.
+func csvColumnErrors(errors Errors, typ reflect.Type) Errors {
+	columns := make(map[string]string)
+	for i := 0; i < typ.NumField(); i++ {
+		if name := typ.Field(i).Tag.Get("csv"); name != "" {
+			columns[typ.Field(i).Name] = name
+		}
+	}
+
+	renamed := *newErrors()
+	for key, val := range errors.Overall {
+		renamed.Overall[key] = val
+	}
+	for key, val := range errors.Fields {
+		if i := strings.Index(key, "]."); i >= 0 {
+			if column, ok := columns[key[i+2:]]; ok {
+				key = key[:i+2] + column
+			}
+		}
+		renamed.Fields[key] = val
+	}
+	return renamed
+}
+
+func ensureSliceOfStructs(obj interface{}) {
+	typ := reflect.TypeOf(obj)
+	if typ.Kind() != reflect.Slice || typ.Elem().Kind() != reflect.Struct {
+		panic("Csv binding models must be a slice of structs")
+	}
+}
+
+var (
+	// Name of the multipart form field holding an uploaded CSV file.
+	CsvField = "file"
+)
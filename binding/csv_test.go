@@ -0,0 +1,129 @@
+package binding
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codegangsta/martini"
+)
+
+func TestCsv(t *testing.T) {
+	payload := "\ufefftitle,content,views,ignored\n" +
+		"Blog Post Title,This is the content,3,x\n" +
+		",This is the content,many,y\n"
+
+	req, err := http.NewRequest("POST", route, strings.NewReader(payload))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Content-Type", "text/csv")
+
+	testCsv(t, req, func(posts []CsvPost, errors Errors) {
+		if len(posts) != 2 {
+			t.Fatalf("Expected two rows to be bound, got %+v", posts)
+		}
+		if posts[0] != (CsvPost{"Blog Post Title", "This is the content", 3}) {
+			t.Errorf("Unexpected first row: %+v", posts[0])
+		}
+		if len(errors.Overall) > 0 {
+			t.Errorf("Did not expect overall errors, got %+v", errors)
+		}
+		if _, ok := errors.Fields["[0].title"]; ok {
+			t.Errorf("Did not expect errors on row 0, got %+v", errors)
+		}
+		if errors.Fields["[1].views"] != IntegerTypeError {
+			t.Errorf("Expected an integer error on row 1, got %+v", errors)
+		}
+		if errors.Fields["[1].title"] != RequireError {
+			t.Errorf("Expected a required error on row 1, got %+v", errors)
+		}
+	})
+}
+
+func TestCsvMultipart(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	file, err := writer.CreateFormFile(CsvField, "posts.csv")
+	if err != nil {
+		t.Error(err)
+	}
+	file.Write([]byte("views,title\n7,Blog Post Title\n"))
+	writer.Close()
+
+	req, err := http.NewRequest("POST", route, body)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	testCsv(t, req, func(posts []CsvPost, errors Errors) {
+		if len(posts) != 1 || posts[0] != (CsvPost{Title: "Blog Post Title", Views: 7}) {
+			t.Errorf("Expected one row to be bound, got %+v", posts)
+		}
+		if errors.Count() > 0 {
+			t.Errorf("Expected no errors, got %+v", errors)
+		}
+	})
+}
+
+func TestCsvShortRow(t *testing.T) {
+	req, err := http.NewRequest("POST", route, strings.NewReader("title,views\nBlog Post Title\nAnother Title,4\n"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	testCsv(t, req, func(posts []CsvPost, errors Errors) {
+		if len(posts) != 2 || posts[1] != (CsvPost{Title: "Another Title", Views: 4}) {
+			t.Errorf("Expected the rows after a short one to be bound, got %+v", posts)
+		}
+		if _, ok := errors.Overall["[0]."+DeserializationError]; !ok {
+			t.Errorf("Expected a deserialization error on row 0, got %+v", errors)
+		}
+		if _, ok := errors.Overall[DeserializationError]; ok {
+			t.Errorf("Did not expect an overall deserialization error, got %+v", errors)
+		}
+	})
+}
+
+func TestCsvMalformed(t *testing.T) {
+	req, err := http.NewRequest("POST", route, strings.NewReader("title,views\n\"Blog \"Post\" Title\n"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	testCsv(t, req, func(posts []CsvPost, errors Errors) {
+		if _, ok := errors.Overall[DeserializationError]; !ok {
+			t.Errorf("Expected a deserialization error, got %+v", errors)
+		}
+	})
+}
+
+func TestCsvNotSlice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Csv to reject a struct")
+		}
+	}()
+	Csv(BlogPost{})
+}
+
+func testCsv(t *testing.T, req *http.Request, handler martini.Handler) {
+	recorder := httptest.NewRecorder()
+	m := martini.Classic()
+	m.Post(route, Csv([]CsvPost{}), handler)
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the handler to run, got status code %d", recorder.Code)
+	}
+}
+
+type CsvPost struct {
+	Title   string `csv:"title" binding:"required"`
+	Content string `csv:"content"`
+	Views   int    `csv:"views"`
+}
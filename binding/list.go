@@ -0,0 +1,163 @@
+package binding
+
+import (
+	"github.com/codegangsta/martini"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// List is middleware to parse the pagination, sorting and filtering
+// parameters of a list endpoint from the query string, for example:
+//
+//	?page=2&per_page=50&sort=-created_at,title&filter[status]=open
+//
+// into a ListQuery. The struct passed in declares the fields that can
+// be used: the form tag names the field in the query string, and the
+// list tag says whether it can be used to "sort", to "filter", or both.
+// Filter values are checked against the type of their field, and
+// pages are limited so that the Offset fits in an int32.
+//
+//	type IssueFields struct {
+//		Status    string `form:"status" list:"filter"`
+//		Priority  int    `form:"priority" list:"filter,sort"`
+//		CreatedAt string `form:"created_at" list:"sort"`
+//	}
+//
+// The resulting ListQuery is mapped to the context along with the
+// Errors, but no error handling is performed here.
+func List(fields interface{}) martini.Handler {
+	ensureNotPointer(fields)
+	sortable, filterable := listFields(reflect.TypeOf(fields))
+
+	return func(context martini.Context, req *http.Request) {
+		query := ListQuery{Filter: make(map[string]string)}
+		errors := newErrors()
+		values := req.URL.Query()
+
+		query.PerPage = parseListInt(values.Get("per_page"), DefaultPerPage, 1, MaxPerPage, "per_page", errors)
+		// Pages are bounded so that the offset fits in an int32
+		maxPage := math.MaxInt32 / query.PerPage
+		if maxPage < 1 {
+			maxPage = 1
+		}
+		query.Page = parseListInt(values.Get("page"), 1, 1, maxPage, "page", errors)
+
+		for _, param := range values["sort"] {
+			for _, name := range strings.Split(param, ",") {
+				name = strings.TrimSpace(name)
+				field := SortField{Field: strings.TrimPrefix(name, "-")}
+				field.Descending = field.Field != name
+
+				if field.Field == "" {
+					continue
+				} else if !sortable[field.Field] {
+					errors.Fields["sort"] = UnknownFieldError
+					continue
+				}
+				query.Sort = append(query.Sort, field)
+			}
+		}
+
+		for key, value := range values {
+			if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+				continue
+			}
+			name := key[len("filter[") : len(key)-1]
+
+			typ, ok := filterable[name]
+			if !ok {
+				errors.Fields[key] = UnknownFieldError
+				continue
+			}
+			setWithProperType(typ.Kind(), value[0], reflect.New(typ).Elem(), key, errors)
+			if _, failed := errors.Fields[key]; !failed {
+				query.Filter[name] = value[0]
+			}
+		}
+
+		context.Map(*errors)
+		context.Map(query)
+	}
+}
+
+// Offset returns the number of items to skip to reach the
+// requested page.
+func (self ListQuery) Offset() int {
+	return (self.Page - 1) * self.PerPage
+}
+
+// listFields reads the form and list tags of a struct type and
+// returns the names of its sortable fields, and the names and types
+// of its filterable fields.
+func listFields(typ reflect.Type) (map[string]bool, map[string]reflect.Type) {
+	sortable := make(map[string]bool)
+	filterable := make(map[string]reflect.Type)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := field.Tag.Get("form")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		for _, usage := range strings.Split(field.Tag.Get("list"), ",") {
+			switch strings.TrimSpace(usage) {
+			case "sort":
+				sortable[name] = true
+			case "filter":
+				filterable[name] = field.Type
+			}
+		}
+	}
+
+	return sortable, filterable
+}
+
+// parseListInt parses a pagination parameter, falling back to def
+// when it is absent. A max of 0 means there is no upper bound.
+func parseListInt(val string, def, min, max int, name string, errors *Errors) int {
+	if val == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		errors.Fields[name] = IntegerTypeError
+		return def
+	}
+	if n < min || (max > 0 && n > max) {
+		errors.Fields[name] = RangeError
+		return def
+	}
+
+	return n
+}
+
+type (
+	// ListQuery is the result of parsing the pagination, sorting and
+	// filtering parameters of a request with the List middleware.
+	ListQuery struct {
+		Page    int
+		PerPage int
+		Sort    []SortField
+		Filter  map[string]string
+	}
+
+	// SortField is a single field of the sort order, in the
+	// order it was requested.
+	SortField struct {
+		Field      string
+		Descending bool
+	}
+)
+
+var (
+	// Number of items per page when the request does not say.
+	DefaultPerPage = 20
+
+	// Largest number of items per page a request may ask for.
+	MaxPerPage = 100
+)
@@ -0,0 +1,127 @@
+package binding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/martini"
+)
+
+func TestList(t *testing.T) {
+	for index, test := range listTests {
+		m := martini.Classic()
+		m.Get(route, List(IssueFields{}), func(query ListQuery, errors Errors) {
+			if query.Page != test.ref.Page || query.PerPage != test.ref.PerPage {
+				t.Errorf("Expected page %d of %d in test case %d, got page %d of %d", test.ref.Page, test.ref.PerPage, index, query.Page, query.PerPage)
+			}
+			if len(query.Sort) != len(test.ref.Sort) {
+				t.Errorf("Expected sort %+v in test case %d, got %+v", test.ref.Sort, index, query.Sort)
+			} else {
+				for i := range query.Sort {
+					if query.Sort[i] != test.ref.Sort[i] {
+						t.Errorf("Expected sort %+v in test case %d, got %+v", test.ref.Sort, index, query.Sort)
+						break
+					}
+				}
+			}
+			if len(query.Filter) != len(test.ref.Filter) {
+				t.Errorf("Expected filter %+v in test case %d, got %+v", test.ref.Filter, index, query.Filter)
+			}
+			for name, value := range test.ref.Filter {
+				if query.Filter[name] != value {
+					t.Errorf("Expected filter %+v in test case %d, got %+v", test.ref.Filter, index, query.Filter)
+				}
+			}
+
+			for key, expected := range test.errors {
+				if errors.Fields[key] != expected {
+					t.Errorf("Expected %s error on %q in test case %d, got %+v", expected, key, index, errors)
+				}
+			}
+			if len(test.errors) == 0 && errors.Count() > 0 {
+				t.Errorf("Test case %d should be OK (0 errors), but had errors: %+v", index, errors)
+			}
+		})
+
+		req, err := http.NewRequest("GET", route+test.query, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		m.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestListOffset(t *testing.T) {
+	if offset := (ListQuery{Page: 3, PerPage: 25}).Offset(); offset != 50 {
+		t.Errorf("Expected offset 50, got %d", offset)
+	}
+}
+
+type (
+	listTestCase struct {
+		query  string
+		ref    ListQuery
+		errors map[string]string
+	}
+
+	IssueFields struct {
+		Status    string `form:"status" list:"filter"`
+		Priority  int    `form:"priority" list:"filter,sort"`
+		CreatedAt string `form:"created_at" list:"sort"`
+		Body      string `form:"body"`
+	}
+)
+
+var listTests = []listTestCase{
+	{
+		"",
+		ListQuery{Page: 1, PerPage: DefaultPerPage},
+		nil,
+	},
+	{
+		"?page=2&per_page=50&sort=-created_at,priority&filter[status]=open&filter[priority]=3",
+		ListQuery{
+			Page:    2,
+			PerPage: 50,
+			Sort:    []SortField{{"created_at", true}, {"priority", false}},
+			Filter:  map[string]string{"status": "open", "priority": "3"},
+		},
+		nil,
+	},
+	{
+		"?sort=priority&sort=-created_at",
+		ListQuery{Page: 1, PerPage: DefaultPerPage, Sort: []SortField{{"priority", false}, {"created_at", true}}},
+		nil,
+	},
+	{
+		"?page=zero&per_page=1000",
+		ListQuery{Page: 1, PerPage: DefaultPerPage},
+		map[string]string{"page": IntegerTypeError, "per_page": RangeError},
+	},
+	{
+		"?page=9223372036854775807&per_page=100",
+		ListQuery{Page: 1, PerPage: 100},
+		map[string]string{"page": RangeError},
+	},
+	{
+		"?page=21474836&per_page=100",
+		ListQuery{Page: 21474836, PerPage: 100},
+		nil,
+	},
+	{
+		"?page=21474837&per_page=100",
+		ListQuery{Page: 1, PerPage: 100},
+		map[string]string{"page": RangeError},
+	},
+	{
+		"?page=0",
+		ListQuery{Page: 1, PerPage: DefaultPerPage},
+		map[string]string{"page": RangeError},
+	},
+	{
+		"?sort=body&filter[created_at]=yesterday&filter[priority]=high",
+		ListQuery{Page: 1, PerPage: DefaultPerPage},
+		map[string]string{"sort": UnknownFieldError, "filter[created_at]": UnknownFieldError, "filter[priority]": IntegerTypeError},
+	},
+}
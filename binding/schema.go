@@ -0,0 +1,150 @@
+package binding
+
+import (
+	"encoding/json"
+	"github.com/codegangsta/martini"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema reflects over a binding struct and returns a JSON Schema
+// document describing it, so the contract of a request can be
+// published without writing the schema by hand. Property names are
+// taken from the json tag, then the form tag, then the field name,
+// and fields tagged binding:"required" are listed as required.
+// Fields skipped by binding or by encoding/json are left out.
+func Schema(obj interface{}) map[string]interface{} {
+	typ := reflect.TypeOf(obj)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	schema := schemaFor(typ, make(map[reflect.Type]bool))
+	schema["$schema"] = SchemaDraft
+	if typ.Name() != "" {
+		schema["title"] = typ.Name()
+	}
+
+	return schema
+}
+
+// Schemas is middleware that serves the JSON Schema of each of the
+// registered types at prefix + "/" + name + ".json", for example
+// /schemas/blogpost.json. Any other request is passed through.
+func Schemas(prefix string, types map[string]interface{}) martini.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	docs := make(map[string][]byte)
+	for name, obj := range types {
+		doc, err := json.MarshalIndent(Schema(obj), "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		docs[prefix+"/"+name+".json"] = doc
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" && req.Method != "HEAD" {
+			return
+		}
+
+		if doc, ok := docs[req.URL.Path]; ok {
+			resp.Header().Set("Content-Type", "application/schema+json")
+			resp.WriteHeader(http.StatusOK)
+			if req.Method == "GET" {
+				resp.Write(doc)
+			}
+		}
+	}
+}
+
+func schemaFor(typ reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes byte slices as base64 strings
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": schemaFor(typ.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(typ.Elem(), seen)}
+	case reflect.Struct:
+		// Recursive types are described only as far as the first repetition
+		if seen[typ] {
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+
+		properties := make(map[string]interface{})
+		required := []string{}
+		addSchemaProperties(typ, properties, &required, seen)
+
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	return map[string]interface{}{}
+}
+
+func addSchemaProperties(typ reflect.Type, properties map[string]interface{}, required *[]string, seen map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		formName := field.Tag.Get("form")
+
+		if jsonName == "-" || (jsonName == "" && formName == "-") {
+			continue
+		}
+
+		// Embedded structs without a name of their own are flattened, like encoding/json does
+		if field.Anonymous && jsonName == "" && field.Type.Kind() == reflect.Struct {
+			addSchemaProperties(field.Type, properties, required, seen)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := jsonName
+		if name == "" {
+			name = formName
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaFor(field.Type, seen)
+		if strings.Index(field.Tag.Get("binding"), "required") > -1 {
+			*required = append(*required, name)
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+const (
+	// The JSON Schema draft that Schema documents conform to.
+	SchemaDraft = "http://json-schema.org/draft-04/schema#"
+)
@@ -0,0 +1,93 @@
+package binding
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/martini"
+)
+
+func TestSchema(t *testing.T) {
+	for index, test := range schemaTests {
+		out, err := json.Marshal(Schema(test.obj))
+		if err != nil {
+			t.Error(err)
+		}
+		if string(out) != test.expected {
+			t.Errorf("Unexpected schema in test case %d:\nexpected %s\ngot      %s", index, test.expected, out)
+		}
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Schemas("/schemas/", map[string]interface{}{"address": Address{}}))
+	m.Get("/schemas/other.json", func() string {
+		return "passed through"
+	})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/schemas/address.json", nil)
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/schema+json" {
+		t.Errorf("Expected a schema content type, got %q", contentType)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil || doc["title"] != "Address" {
+		t.Errorf("Expected the Address schema, got %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/schemas/other.json", nil)
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Body.String() != "passed through" {
+		t.Errorf("Expected unregistered paths to pass through, got %q", recorder.Body.String())
+	}
+}
+
+type (
+	schemaTestCase struct {
+		obj      interface{}
+		expected string
+	}
+
+	Timestamps struct {
+		Created time.Time `json:"created"`
+	}
+
+	Comment struct {
+		Timestamps
+		Author  *User             `json:"author" binding:"required"`
+		Body    string            `form:"body" binding:"required"`
+		Score   float64           `json:",omitempty"`
+		Tags    []string          `json:"tags"`
+		Meta    map[string]int    `json:"meta"`
+		Replies []Comment         `json:"replies"`
+		Secret  string            `json:"-"`
+		Ignored map[string]string `form:"-"`
+		private string
+	}
+)
+
+var schemaTests = []schemaTestCase{
+	{
+		Address{},
+		`{"$schema":"http://json-schema.org/draft-04/schema#","properties":{"street1":{"type":"string"},"street2":{"type":"string"}},"required":["street1"],"title":"Address","type":"object"}`,
+	},
+	{
+		&BlogPost{},
+		`{"$schema":"http://json-schema.org/draft-04/schema#","properties":{"content":{"type":"string"},"multiple":{"items":{"type":"integer"},"type":"array"},"title":{"type":"string"},"views":{"type":"integer"}},"required":["title"],"title":"BlogPost","type":"object"}`,
+	},
+	{
+		Comment{},
+		`{"$schema":"http://json-schema.org/draft-04/schema#","properties":{"Score":{"type":"number"},"author":{"properties":{"address":{"properties":{"street1":{"type":"string"},"street2":{"type":"string"}},"required":["street1"],"type":"object"},"name":{"type":"string"}},"required":["name","address"],"type":"object"},"body":{"type":"string"},"created":{"format":"date-time","type":"string"},"meta":{"additionalProperties":{"type":"integer"},"type":"object"},"replies":{"items":{"type":"object"},"type":"array"},"tags":{"items":{"type":"string"},"type":"array"}},"required":["author","body"],"title":"Comment","type":"object"}`,
+	},
+}
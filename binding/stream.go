@@ -0,0 +1,122 @@
+package binding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/codegangsta/martini"
+	"net/http"
+	"reflect"
+)
+
+// NDJson is middleware to deserialize newline-delimited JSON from
+// the request body, one struct per line. Rather than reading the whole
+// body up front, it maps a *Stream to the context which decodes and
+// validates the next line each time its Next method is called, so
+// large imports can be processed record by record. Errors are reported
+// per element: a bad line does not stop the rest of the stream.
+func NDJson(obj interface{}) martini.Handler {
+	return func(context martini.Context, req *http.Request) {
+		ensureNotPointer(obj)
+		context.Map(newStream(context, req, reflect.TypeOf(obj)))
+	}
+}
+
+// Stream iterates over the elements of a newline-delimited JSON
+// request body. Blank lines are skipped.
+//
+//	for stream.Next() {
+//		if errs := stream.Errors(); errs.Count() > 0 {
+//			// report errs for element stream.Index()
+//			continue
+//		}
+//		record := stream.Value().(Record)
+//	}
+//	if err := stream.Err(); err != nil {
+//		// the body could not be read to the end
+//	}
+type Stream struct {
+	context martini.Context
+	req     *http.Request
+	typ     reflect.Type
+	scanner *bufio.Scanner
+	index   int
+	value   reflect.Value
+	errors  *Errors
+	err     error
+}
+
+func newStream(context martini.Context, req *http.Request, typ reflect.Type) *Stream {
+	scanner := bufio.NewScanner(bytes.NewReader(nil))
+	if req.Body != nil {
+		scanner = bufio.NewScanner(req.Body)
+	}
+	scanner.Buffer(nil, MaxLineSize)
+
+	return &Stream{
+		context: context,
+		req:     req,
+		typ:     typ,
+		scanner: scanner,
+		index:   -1,
+	}
+}
+
+// Next decodes and validates the next element of the stream. It
+// returns false when the body is exhausted or could not be read.
+func (s *Stream) Next() bool {
+	for s.scanner.Scan() {
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		s.index++
+		s.value = reflect.New(s.typ)
+		s.errors = newErrors()
+
+		if err := json.Unmarshal(line, s.value.Interface()); err != nil {
+			s.errors.Overall[DeserializationError] = err.Error()
+		} else {
+			s.errors.combine(*validate(s.context, s.req, s.value.Interface()))
+		}
+		return true
+	}
+
+	s.value = reflect.Value{}
+	s.errors = nil
+	s.err = s.scanner.Err()
+	return false
+}
+
+// Index returns the zero-based position of the current element,
+// not counting blank lines.
+func (s *Stream) Index() int {
+	return s.index
+}
+
+// Value returns the current element, a value of the type that
+// was passed to NDJson. It returns nil before the first call to
+// Next, or once Next has returned false.
+func (s *Stream) Value() interface{} {
+	if !s.value.IsValid() {
+		return nil
+	}
+	return s.value.Elem().Interface()
+}
+
+// Errors returns the deserialization and validation errors
+// of the current element. They are empty before the first call
+// to Next, or once Next has returned false.
+func (s *Stream) Errors() Errors {
+	if s.errors == nil {
+		return *newErrors()
+	}
+	return *s.errors
+}
+
+// Err returns the error, if any, that stopped the stream before
+// the end of the request body was reached.
+func (s *Stream) Err() error {
+	return s.err
+}
@@ -0,0 +1,78 @@
+package binding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codegangsta/martini"
+)
+
+func TestNDJson(t *testing.T) {
+	payload := `{"title":"Blog Post Title","content":"This is the content"}
+
+{"title":"", "content":"This is the content"}
+{ bad JSON
+{"title":"Another Title","content":"More content"}
+`
+	var titles []string
+	failed := map[int]Errors{}
+
+	m := martini.Classic()
+	m.Post(route, NDJson(BlogPost{}), func(stream *Stream) {
+		if stream.Value() != nil || stream.Errors().Count() > 0 {
+			t.Error("Expected no element before the first call to Next")
+		}
+		for stream.Next() {
+			if errs := stream.Errors(); errs.Count() > 0 {
+				failed[stream.Index()] = errs
+				continue
+			}
+			titles = append(titles, stream.Value().(BlogPost).Title)
+		}
+		if err := stream.Err(); err != nil {
+			t.Error("Unexpected stream error:", err)
+		}
+		if stream.Value() != nil || stream.Errors().Count() > 0 {
+			t.Error("Expected no element once the stream is exhausted")
+		}
+	})
+
+	req, err := http.NewRequest("POST", route, strings.NewReader(payload))
+	if err != nil {
+		t.Error(err)
+	}
+	m.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(titles) != 2 || titles[0] != "Blog Post Title" || titles[1] != "Another Title" {
+		t.Errorf("Expected two valid elements, got %v", titles)
+	}
+	if _, ok := failed[1].Fields["Title"]; !ok {
+		t.Errorf("Expected element 1 to fail validation, got %+v", failed[1])
+	}
+	if _, ok := failed[2].Overall[DeserializationError]; !ok {
+		t.Errorf("Expected element 2 to fail deserialization, got %+v", failed[2])
+	}
+}
+
+func TestNDJsonLineTooLong(t *testing.T) {
+	defer func(size int) { MaxLineSize = size }(MaxLineSize)
+	MaxLineSize = 16
+
+	m := martini.Classic()
+	m.Post(route, NDJson(BlogPost{}), func(stream *Stream) {
+		for stream.Next() {
+			t.Error("Did not expect an element from an oversized line")
+		}
+		if stream.Err() == nil {
+			t.Error("Expected the stream to stop with an error")
+		}
+	})
+
+	req, err := http.NewRequest("POST", route, strings.NewReader(`{"title":"Blog Post Title"}`))
+	if err != nil {
+		t.Error(err)
+	}
+	m.ServeHTTP(httptest.NewRecorder(), req)
+}
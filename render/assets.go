@@ -0,0 +1,133 @@
+package render
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Assets maps the paths of static files, relative to their directory and using forward slashes, to the names of
+// their fingerprinted copies, such as "css/app.css" to "css/app.5ba93c9db0.css". Since the name of a copy changes with
+// its contents, it can be cached forever.
+type Assets map[string]string
+
+// LoadAssets reads a manifest of Assets from a JSON file, such as the one written by render-assets.
+func LoadAssets(file string) (Assets, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets Assets
+	if err := json.Unmarshal(buf, &assets); err != nil {
+		return nil, fmt.Errorf("render: invalid asset manifest %s: %v", file, err)
+	}
+	return assets, nil
+}
+
+// HashAssets fingerprints every file under dir, without copying them. Fingerprinted copies already in dir are left out.
+func HashAssets(dir string) (Assets, error) {
+	hashes, err := hashAssets(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := Assets{}
+	for name, hash := range hashes {
+		assets[name] = fingerprintedName(name, hash)
+	}
+	return assets, nil
+}
+
+// WriteAssets fingerprints every file under dir, and writes their fingerprinted copies under out, which may be dir
+// itself.
+func WriteAssets(dir, out string) (Assets, error) {
+	assets, err := HashAssets(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, fingerprinted := range assets {
+		buf, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+
+		file := filepath.Join(out, filepath.FromSlash(fingerprinted))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(file, buf, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return assets, nil
+}
+
+// hashAssets returns the hash of the contents of every file under dir, by path, skipping fingerprinted copies.
+func hashAssets(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		r, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		buf, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		name, hash := filepath.ToSlash(r), fmt.Sprintf("%x", sha1.Sum(buf))[:10]
+		if strings.HasSuffix(strings.TrimSuffix(name, path.Ext(name)), "."+hash) {
+			// a fingerprinted copy written by an earlier run
+			return nil
+		}
+		hashes[name] = hash
+		return nil
+	})
+	return hashes, err
+}
+
+// fingerprintedName inserts hash before the extension of name.
+func fingerprintedName(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// assetFunc returns the asset template function, which turns the path of a static file into its URL. The URL points
+// to the fingerprinted copy listed in Options.AssetManifest, or else has the hash of the file in Options.AssetDirectory
+// as a query string, since there is no copy. Unknown files, and any file without either option, get their plain URL.
+func assetFunc(opt Options) (func(name string) string, error) {
+	prefix := strings.TrimSuffix(opt.AssetPrefix, "/") + "/"
+
+	var assets Assets
+	var hashes map[string]string
+	var err error
+	if len(opt.AssetManifest) > 0 {
+		assets, err = LoadAssets(opt.AssetManifest)
+	} else if len(opt.AssetDirectory) > 0 {
+		hashes, err = hashAssets(opt.AssetDirectory)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func(name string) string {
+		name = strings.TrimPrefix(name, "/")
+		if fingerprinted, ok := assets[name]; ok {
+			return prefix + fingerprinted
+		} else if hash, ok := hashes[name]; ok {
+			return prefix + name + "?v=" + hash
+		}
+		return prefix + name
+	}, nil
+}
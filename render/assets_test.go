@@ -0,0 +1,91 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_HashAssets(t *testing.T) {
+	assets, err := HashAssets("fixtures/assets/public")
+
+	expect(t, err, nil)
+	expect(t, len(assets), 2)
+	expect(t, assets["css/app.css"], "css/app.72a961e43b.css")
+	expect(t, assets["js/app.js"], "js/app.028d456afa.js")
+}
+
+func Test_WriteAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assets, err := WriteAssets("fixtures/assets/public", dir)
+	expect(t, err, nil)
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "css", "app.72a961e43b.css"))
+	expect(t, err, nil)
+	expect(t, string(buf), "body { color: black; }\n")
+
+	// copies are skipped when hashing again
+	writeTemplate(t, filepath.Join(dir, "css", "app.css"), "body { color: black; }\n", time.Now())
+	again, err := HashAssets(dir)
+	expect(t, err, nil)
+	expect(t, len(again), 1)
+	expect(t, again["css/app.css"], assets["css/app.css"])
+}
+
+func Test_Render_Asset(t *testing.T) {
+	tests := []struct {
+		opt  Options
+		body string
+	}{
+		{
+			Options{},
+			`<link href="/css/app.css"><script src="/js/app.js"></script>` + "\n",
+		},
+		{
+			Options{AssetManifest: "fixtures/assets/manifest.json", AssetPrefix: "https://cdn.example.com/static/"},
+			`<link href="https://cdn.example.com/static/css/app.0123456789.css"><script src="https://cdn.example.com/static/js/app.js"></script>` + "\n",
+		},
+		{
+			Options{AssetDirectory: "fixtures/assets/public"},
+			`<link href="/css/app.css?v=72a961e43b"><script src="/js/app.js?v=028d456afa"></script>` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.opt.Directory = "fixtures/assets/templates"
+		m := martini.Classic()
+		m.Use(Renderer(test.opt))
+
+		// routing
+		m.Get("/foobar", func(r Render) {
+			r.HTML(200, "page", nil)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar", nil)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		expect(t, res.Body.String(), test.body)
+	}
+}
+
+func Test_Render_Asset_Missing_Manifest(t *testing.T) {
+	defer func() {
+		refute(t, recover(), nil)
+	}()
+	Renderer(Options{
+		Directory:     "fixtures/assets/templates",
+		AssetManifest: "fixtures/assets/missing.json",
+	})
+}
@@ -0,0 +1,48 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"text/template/parse"
+)
+
+// Bundle holds the contents of template files by their path, using forward slashes and keeping the extension, such as
+// "admin/index.tmpl". Set it as Options.Bundle to load templates from memory rather than from disk; they are named and
+// filtered by extension exactly as if they were read from a directory. Options.Directory defaults to the root of the
+// bundle, and Directories, Namespaces and Themes are looked up within it.
+//
+// Bundles are usually generated into Go source by the render-bundle command, so that templates ship inside the binary:
+//
+//	//go:generate go run github.com/codegangsta/martini-contrib/render/cmd/render-bundle -dir templates
+type Bundle map[string]string
+
+// NewBundle reads every template file that Renderer would load with the given options (using Directory or FileSystem,
+// Extensions, TextExtensions and Delims) and checks that each of them parses. Template functions are not checked, since Funcs are
+// usually not known outside of the application.
+func NewBundle(options Options) (Bundle, error) {
+	opt := prepareOptions([]Options{options})
+	b := Bundle{}
+
+	err := walkTemplates(opt, opt.Directory, func(r string, info os.FileInfo, read func() ([]byte, error)) error {
+		if _, _, ok := templateName(opt, r); !ok {
+			return nil
+		}
+
+		buf, err := read()
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(r)
+		tree := parse.New(name)
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(string(buf), opt.Delims.Left, opt.Delims.Right, map[string]*parse.Tree{}); err != nil {
+			return err
+		}
+
+		b[name] = string(buf)
+		return nil
+	})
+
+	return b, err
+}
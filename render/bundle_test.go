@@ -0,0 +1,128 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_NewBundle(t *testing.T) {
+	b, err := NewBundle(Options{
+		Directory: "fixtures/basic",
+	})
+
+	expect(t, err, nil)
+	expect(t, b["hello.tmpl"], "<h1>Hello {{.}}</h1>\n")
+	expect(t, b["admin/index.tmpl"], "<h1>Admin {{.}}</h1>\n")
+	_, ok := b["hypertext.html"]
+	expect(t, ok, false)
+
+	b, err = NewBundle(Options{
+		Directory:  "fixtures/basic",
+		Extensions: []string{".html"},
+	})
+
+	expect(t, err, nil)
+	expect(t, len(b), 1)
+	expect(t, b["hypertext.html"], "Hypertext!\n")
+}
+
+func Test_NewBundle_Text(t *testing.T) {
+	b, err := NewBundle(Options{
+		Directory:      "fixtures/text",
+		TextExtensions: []string{".txt.tmpl"},
+	})
+
+	expect(t, err, nil)
+	expect(t, len(b), 3)
+	expect(t, b["welcome.txt.tmpl"], "Hello {{.Name}} & <friends>\n{{ template \"signature\" }}")
+}
+
+func Test_NewBundle_Parse_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// unknown functions are fine, broken actions are not
+	writeTemplate(t, filepath.Join(dir, "funcs.tmpl"), "{{ myCustomFunc }}", time.Now())
+	_, err = NewBundle(Options{
+		Directory: dir,
+	})
+	expect(t, err, nil)
+
+	writeTemplate(t, filepath.Join(dir, "delims.tmpl"), "{[{ .Name }]}{{ end }}", time.Now())
+	_, err = NewBundle(Options{
+		Directory: dir,
+		Delims:    Delims{"{[{", "}]}"},
+	})
+	expect(t, err, nil)
+
+	_, err = NewBundle(Options{
+		Directory: dir,
+	})
+	refute(t, err, nil)
+}
+
+func Test_Render_Bundle(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Layout: "layout",
+		Bundle: Bundle{
+			"layout.tmpl":      "head\n{{ yield }}\nfoot\n",
+			"admin/index.tmpl": "<h1>Admin {{.}}</h1>\n",
+			"notes.txt":        "{{ ignored",
+		},
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "admin/index", "jeremy")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "head\n<h1>Admin jeremy</h1>\n\nfoot\n")
+}
+
+func Test_Render_Bundle_Namespaces(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:  "site",
+		Namespaces: map[string]string{"mail": "mail"},
+		Bundle: Bundle{
+			"site/hello.tmpl":   "<h1>Hello {{.}}</h1>\n",
+			"mail/welcome.tmpl": "Welcome {{.}}!\n",
+		},
+	}))
+
+	// routing
+	m.Get("/hello", func(r Render) {
+		r.HTML(200, "hello", "jeremy")
+	})
+	m.Get("/welcome", func(r Render) {
+		r.HTML(200, "mail:welcome", "jeremy")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	m.ServeHTTP(res, req)
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Hello jeremy</h1>\n")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/welcome", nil)
+	m.ServeHTTP(res, req)
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "Welcome jeremy!\n")
+}
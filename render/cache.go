@@ -0,0 +1,134 @@
+package render
+
+import (
+	"container/list"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFragmentCacheSize is the number of fragments kept by the cache of a Renderer without Options.FragmentCache.
+var DefaultFragmentCacheSize = 1000
+
+// FragmentCache stores the template fragments rendered by the cache template function. Renderer maps it into the
+// Martini handler chain, so that handlers can invalidate fragments when their data changes. Fragments are stored under
+// their key followed by "\x00" and the theme they were rendered with, so that themes don't share fragments, and
+// DeletePrefix("nav") still invalidates "nav" for every theme.
+type FragmentCache interface {
+	// Get returns the fragment stored under key, unless it is missing or expired.
+	Get(key string) (string, bool)
+	// Set stores a fragment under key, for the given time to live.
+	Set(key string, fragment string, ttl time.Duration)
+	// DeletePrefix removes every fragment whose key starts with prefix, or every fragment for "".
+	DeletePrefix(prefix string)
+}
+
+// NewLRUCache returns an in-memory FragmentCache holding at most size fragments, which evicts the least recently
+// used fragment when it is full.
+func NewLRUCache(size int) FragmentCache {
+	return &lruCache{size: size, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+type lruCache struct {
+	sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// most recently used first
+	order *list.List
+}
+
+type lruEntry struct {
+	key      string
+	fragment string
+	expires  time.Time
+}
+
+func (c *lruCache) Get(key string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := e.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(e)
+		return "", false
+	}
+
+	c.order.MoveToFront(e)
+	return entry.fragment, true
+}
+
+func (c *lruCache) Set(key string, fragment string, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry := &lruEntry{key, fragment, time.Now().Add(ttl)}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lruCache) DeletePrefix(prefix string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, e := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(e)
+		}
+	}
+}
+
+func (c *lruCache) remove(e *list.Element) {
+	delete(c.entries, e.Value.(*lruEntry).key)
+	c.order.Remove(e)
+}
+
+// cache lets templates render another template once, and reuse its output for a while, with
+// {{ cache "key" "5m" "name" data }}.
+func (r *renderer) cache(key string, ttl interface{}, name string, data ...interface{}) (template.HTML, error) {
+	var d time.Duration
+	switch t := ttl.(type) {
+	case string:
+		var err error
+		if d, err = time.ParseDuration(t); err != nil {
+			return "", err
+		}
+	case time.Duration:
+		d = t
+	default:
+		return "", fmt.Errorf("cache takes a time to live such as \"5m\", got %T", ttl)
+	}
+
+	var binding interface{}
+	if len(data) == 1 {
+		binding = data[0]
+	} else if len(data) > 1 {
+		return "", fmt.Errorf("cache takes at most one data argument, got %d", len(data))
+	}
+
+	key += "\x00" + r.theme
+	if fragment, ok := r.fragments.Get(key); ok {
+		// return safe html here since we rendered it from our own template
+		return template.HTML(fragment), nil
+	}
+
+	buf, err := r.execute(name, binding)
+	if err != nil {
+		return "", err
+	}
+	r.fragments.Set(key, buf.String(), d)
+	return template.HTML(buf.String()), nil
+}
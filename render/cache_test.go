@@ -0,0 +1,135 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_LRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set("a", "A", time.Hour)
+	c.Set("b", "B", time.Hour)
+	fragment, ok := c.Get("a")
+	expect(t, ok, true)
+	expect(t, fragment, "A")
+
+	// b is the least recently used
+	c.Set("c", "C", time.Hour)
+	_, ok = c.Get("b")
+	expect(t, ok, false)
+	_, ok = c.Get("a")
+	expect(t, ok, true)
+
+	c.Set("c", "D", -time.Second)
+	_, ok = c.Get("c")
+	expect(t, ok, false)
+
+	c.Set("user/1/nav", "1", time.Hour)
+	c.Set("user/2/nav", "2", time.Hour)
+	c.DeletePrefix("user/1/")
+	_, ok = c.Get("user/1/nav")
+	expect(t, ok, false)
+	_, ok = c.Get("user/2/nav")
+	expect(t, ok, true)
+
+	c.DeletePrefix("")
+	_, ok = c.Get("user/2/nav")
+	expect(t, ok, false)
+}
+
+func Test_Render_Cache(t *testing.T) {
+	count := 0
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/cache",
+		Funcs: []template.FuncMap{
+			{
+				"count": func() int {
+					count++
+					return count
+				},
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/page", func(r Render) {
+		r.HTML(200, "page", "jeremy")
+	})
+	m.Get("/bad", func(r Render) {
+		r.HTML(200, "bad", "jeremy")
+	})
+	m.Post("/nav", func(fragments FragmentCache) {
+		fragments.DeletePrefix("na")
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	expect(t, serve("GET", "/page").Body.String(), "<nav>1 jeremy</nav>")
+	expect(t, serve("GET", "/page").Body.String(), "<nav>1 jeremy</nav>")
+	serve("POST", "/nav")
+	expect(t, serve("GET", "/page").Body.String(), "<nav>2 jeremy</nav>")
+
+	expect(t, serve("GET", "/bad").Code, 500)
+}
+
+func Test_Render_Cache_Themes(t *testing.T) {
+	count := 0
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "site",
+		Themes:    map[string]string{"acme": "acme"},
+		ThemeSelector: func(req *http.Request) string {
+			return req.URL.Query().Get("theme")
+		},
+		Bundle: Bundle{
+			"site/page.tmpl": `{{ cache "hdr" "1h" "hdr" . }}`,
+			"site/hdr.tmpl":  "hdr {{ count }}",
+			"acme/hdr.tmpl":  "acme-hdr {{ count }}",
+		},
+		Funcs: []template.FuncMap{
+			{
+				"count": func() int {
+					count++
+					return count
+				},
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/page", func(r Render) {
+		r.HTML(200, "page", nil)
+	})
+	m.Post("/hdr", func(fragments FragmentCache) {
+		fragments.DeletePrefix("hdr")
+	})
+
+	serve := func(method, path string) string {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		m.ServeHTTP(res, req)
+		return res.Body.String()
+	}
+
+	// each theme renders and caches its own fragment
+	expect(t, serve("GET", "/page?theme=acme"), "acme-hdr 1")
+	expect(t, serve("GET", "/page"), "hdr 2")
+	expect(t, serve("GET", "/page?theme=acme"), "acme-hdr 1")
+	expect(t, serve("GET", "/page"), "hdr 2")
+
+	// a key is invalidated for every theme
+	serve("POST", "/hdr")
+	expect(t, serve("GET", "/page?theme=acme"), "acme-hdr 3")
+	expect(t, serve("GET", "/page"), "hdr 4")
+}
@@ -0,0 +1,44 @@
+// Command render-assets writes fingerprinted copies of the static files of a directory, whose names change with their
+// contents so that they can be cached forever, along with a JSON manifest of them. Set the manifest as
+// render.Options.AssetManifest, and the asset template function links the copies:
+//
+//	//go:generate go run github.com/codegangsta/martini-contrib/render/cmd/render-assets -dir public -manifest assets.json
+//
+//	m.Use(render.Renderer(render.Options{
+//	  AssetManifest: "assets.json",
+//	}))
+//
+// The copies are written next to the files unless -o is given. Running it again skips the copies it wrote before.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/codegangsta/martini-contrib/render"
+	"io/ioutil"
+	"log"
+)
+
+func main() {
+	dir := flag.String("dir", "public", "directory of the static files")
+	out := flag.String("o", "", "directory to write the fingerprinted copies to, defaults to -dir")
+	manifest := flag.String("manifest", "assets.json", "file to write the manifest to")
+	flag.Parse()
+
+	if *out == "" {
+		*out = *dir
+	}
+
+	assets, err := render.WriteAssets(*dir, *out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	buf, err := json.MarshalIndent(assets, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*manifest, append(buf, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
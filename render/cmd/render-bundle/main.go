@@ -0,0 +1,85 @@
+// Command render-bundle embeds a directory of templates into a Go source file, as a render.Bundle that can be set
+// as render.Options.Bundle. Templates are picked with the same extension rules as render.Renderer, and each of them
+// is parsed with the given delimiters, so a broken template fails the build instead of the server start.
+//
+// It is meant to be run by go generate, next to the code that sets up the renderer:
+//
+//	//go:generate go run github.com/codegangsta/martini-contrib/render/cmd/render-bundle -dir templates -o templates_bundle.go
+//
+//	m.Use(render.Renderer(render.Options{
+//	  Bundle: Templates,
+//	}))
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/codegangsta/martini-contrib/render"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	dir := flag.String("dir", "templates", "directory to load the templates from")
+	extensions := flag.String("ext", ".tmpl", "comma separated extensions of the template files")
+	textExtensions := flag.String("text-ext", "", "comma separated extensions of the text template files, such as .txt.tmpl")
+	left := flag.String("left", "", "left action delimiter, defaults to {{")
+	right := flag.String("right", "", "right action delimiter, defaults to }}")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated file, defaults to the package running go generate")
+	name := flag.String("var", "Templates", "name of the generated render.Bundle variable")
+	out := flag.String("o", "templates_bundle.go", "file to write")
+	flag.Parse()
+
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	opt := render.Options{
+		Directory:  *dir,
+		Extensions: strings.Split(*extensions, ","),
+		Delims:     render.Delims{Left: *left, Right: *right},
+	}
+	if *textExtensions != "" {
+		opt.TextExtensions = strings.Split(*textExtensions, ",")
+	}
+
+	bundle, err := render.NewBundle(opt)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := generate(bundle, *pkg, *name, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the formatted Go source declaring the bundle.
+func generate(bundle render.Bundle, pkg, name, dir string) ([]byte, error) {
+	paths := make([]string, 0, len(bundle))
+	for path := range bundle {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by render-bundle; DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintf(buf, "import \"github.com/codegangsta/martini-contrib/render\"\n\n")
+	fmt.Fprintf(buf, "// %s holds the templates of the %q directory, for use as render.Options.Bundle.\n", name, dir)
+	fmt.Fprintf(buf, "var %s = render.Bundle{\n", name)
+	for _, path := range paths {
+		fmt.Fprintf(buf, "%q: %q,\n", path, bundle[path])
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
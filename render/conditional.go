@@ -0,0 +1,66 @@
+package render
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (r *renderer) LastModified(t time.Time) {
+	r.lastModified = t
+}
+
+// writeBody writes a rendered body with the given status and content type, along with its ETag and Last-Modified
+// headers. If the request is conditional and the client's copy is still fresh, a 304 Not Modified is written instead.
+func (r *renderer) writeBody(status int, contentType string, body []byte) {
+	if r.opt.ETag {
+		r.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum(body)))
+	}
+	if !r.lastModified.IsZero() {
+		r.Header().Set("Last-Modified", r.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if status == http.StatusOK && r.notModified() {
+		r.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	r.Header().Set(ContentType, contentType)
+	r.WriteHeader(status)
+	r.Write(body)
+}
+
+// notModified reports whether the response already set up is not modified according to the If-None-Match header of
+// the request, or to its If-Modified-Since header in the absence of If-None-Match.
+func (r *renderer) notModified() bool {
+	if r.req == nil || (r.req.Method != "GET" && r.req.Method != "HEAD") {
+		return false
+	}
+
+	if header := r.req.Header.Get("If-None-Match"); header != "" {
+		etag := r.Header().Get("ETag")
+		return etag != "" && etagMatches(header, etag)
+	}
+
+	if header := r.req.Header.Get("If-Modified-Since"); header != "" && !r.lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		// Last-Modified only has a precision of a second
+		return err == nil && !r.lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagMatches reports whether any of the entity tags of an If-None-Match header matches etag, using the weak comparison.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Render_ETag(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/basic",
+		ETag:      true,
+	}))
+
+	// routing
+	m.Get("/html", func(r Render) {
+		r.HTML(200, "hello", "jeremy")
+	})
+	m.Get("/json", func(r Render) {
+		r.JSON(200, Greeting{"hello", "world"})
+	})
+	m.Get("/xml", func(r Render) {
+		r.XML(200, Greeting{"hello", "world"})
+	})
+	m.Get("/created", func(r Render) {
+		r.JSON(201, Greeting{"hello", "world"})
+	})
+
+	for _, path := range []string{"/html", "/json", "/xml"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		etag := res.Header().Get("ETag")
+		expect(t, len(etag), 42)
+
+		res = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", `"other", `+etag)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 304)
+		expect(t, res.Header().Get("ETag"), etag)
+		expect(t, res.Header().Get(ContentType), "")
+		expect(t, res.Body.Len(), 0)
+
+		res = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", `"other"`)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		refute(t, res.Body.Len(), 0)
+	}
+
+	// only a 200 OK becomes a 304 Not Modified
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/created", nil)
+	req.Header.Set("If-None-Match", "*")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 201)
+	expect(t, res.Body.String(), `{"one":"hello","two":"world"}`)
+}
+
+func Test_Render_LastModified(t *testing.T) {
+	modified := time.Date(2014, 1, 2, 3, 4, 5, 600, time.UTC)
+
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/basic",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.LastModified(modified)
+		r.HTML(200, "hello", "jeremy")
+	})
+
+	tests := []struct {
+		since string
+		code  int
+	}{
+		{"", 200},
+		{"Thu, 02 Jan 2014 03:04:05 GMT", 304},
+		{"Fri, 03 Jan 2014 00:00:00 GMT", 304},
+		{"Thu, 02 Jan 2014 03:04:04 GMT", 200},
+		{"not a date", 200},
+	}
+	for _, test := range tests {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar", nil)
+		if test.since != "" {
+			req.Header.Set("If-Modified-Since", test.since)
+		}
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, test.code)
+		expect(t, res.Header().Get("Last-Modified"), "Thu, 02 Jan 2014 03:04:05 GMT")
+	}
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"html/template"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorData is the data error templates are rendered with.
+type ErrorData struct {
+	// Status code of the response, such as 404.
+	Status int
+	// StatusText is the standard text of the status code, such as "Not Found".
+	StatusText string
+	// Err is the error which prevented a response from being rendered, for a 500 Internal Server Error. It is nil
+	// otherwise. Beware of showing it to your users, since it may reveal the internals of your application.
+	Err error
+}
+
+// Number of source lines shown before and after the offending one on the developer error page
+const errorContextLines = 3
+
+// template errors start with the name of the template and a line number, such as `template: hello:1: ...`
+var templateErrorLocation = regexp.MustCompile(`^(?:html/)?template: ?(.+?):(\d+):`)
+
+// developerErrorPage shows why a response could not be rendered, in development.
+var developerErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>500 Internal Server Error</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f6f6f6; padding: 1em; overflow: auto; }
+.current { background: #fdd; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>500 Internal Server Error</h1>
+<pre class="error">{{ .Err }}</pre>
+{{ if .Template }}<h2>{{ .Template }}</h2>
+<pre class="source">{{ range .Lines }}<span{{ if .Current }} class="current"{{ end }}>{{ printf "%4d" .Number }}  {{ .Text }}</span>
+{{ end }}</pre>
+{{ end }}</body>
+</html>
+`))
+
+// sourceLine is a line of template source on the developer error page.
+type sourceLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+// Error writes the given HTTP status to the current ResponseWriter, with the body of the error template for that
+// status if Options.ErrorTemplates is set and there is one.
+func (r *renderer) Error(status int) {
+	if !r.errorTemplate(status, nil) {
+		r.WriteHeader(status)
+	}
+}
+
+// renderFailed writes a 500 Internal Server Error because err prevented a response from being rendered. In
+// development, browsers get the developer error page, showing the offending template source if there is one. Otherwise
+// the error template for 500 is rendered if there is one, or else the error message.
+func (r *renderer) renderFailed(err error, text bool) {
+	if martini.Env == martini.Dev && r.acceptsHTML() {
+		r.developerError(err, text)
+	} else if !r.errorTemplate(http.StatusInternalServerError, err) {
+		http.Error(r, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// errorTemplate renders the error template for status, and reports whether it did.
+func (r *renderer) errorTemplate(status int, err error) bool {
+	if len(r.opt.ErrorTemplates) == 0 {
+		return false
+	}
+	r.load()
+	if r.t == nil || r.templateErr != nil {
+		return false
+	}
+	name := r.opt.ErrorTemplates + strconv.Itoa(status)
+	if r.t.Lookup(name) == nil {
+		return false
+	}
+
+	out, rerr := r.renderHTML(name, ErrorData{status, http.StatusText(status), err}, nil)
+	if rerr != nil {
+		// the error template is broken too, so fall back to the bare status
+		return false
+	}
+
+	r.Header().Set(ContentType, ContentHTML+r.compiledCharset)
+	r.WriteHeader(status)
+	io.Copy(r, out)
+	return true
+}
+
+// acceptsHTML reports whether the request explicitly accepts HTML, as browsers do.
+func (r *renderer) acceptsHTML() bool {
+	if r.req == nil {
+		return false
+	}
+	match, ok := bestMatch(parseAccept(r.req.Header.Get("Accept")), ContentHTML)
+	return ok && match.specificity() == 2 && match.quality > 0
+}
+
+// developerError writes the developer error page for err. text tells whether a text template was being rendered, to
+// find the offending source when HTML and text templates share a name.
+func (r *renderer) developerError(err error, text bool) {
+	data := struct {
+		Err      error
+		Template string
+		Lines    []sourceLine
+	}{Err: err}
+
+	r.load()
+	if m := templateErrorLocation.FindStringSubmatch(err.Error()); m != nil {
+		source, ok := r.sources[templateSource{m[1], text}]
+		if !ok {
+			source, ok = r.sources[templateSource{m[1], !text}]
+		}
+		line, _ := strconv.Atoi(m[2])
+		if ok {
+			data.Template = m[1]
+			for i, l := range strings.Split(source, "\n") {
+				if n := i + 1; n >= line-errorContextLines && n <= line+errorContextLines {
+					data.Lines = append(data.Lines, sourceLine{n, l, n == line})
+				}
+			}
+		}
+	}
+
+	r.Header().Set(ContentType, ContentHTML+r.compiledCharset)
+	r.WriteHeader(http.StatusInternalServerError)
+	developerErrorPage.Execute(r, data)
+}
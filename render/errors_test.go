@@ -0,0 +1,143 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Render_Error_Templates(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:      "fixtures/errors",
+		ErrorTemplates: "errors/",
+	}))
+
+	// routing
+	m.Get("/404", func(r Render) {
+		r.Error(404)
+	})
+	m.Get("/403", func(r Render) {
+		r.Error(403)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/404", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 404)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<h1>404 Not Found</h1>\n")
+
+	// no template for this one
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/403", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 403)
+	expect(t, res.Body.String(), "")
+}
+
+func Test_Render_Error_Template_Render_Failure(t *testing.T) {
+	defer func(env string) { martini.Env = env }(martini.Env)
+	martini.Env = martini.Prod
+
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:      "fixtures/errors",
+		ErrorTemplates: "errors/",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "broken", struct{}{})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+	req.Header.Set("Accept", "text/html")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+	expect(t, res.Body.String(), "<h1>Sorry, something went wrong</h1>\n")
+}
+
+func Test_Render_Developer_Error(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:      "fixtures/errors",
+		TextExtensions: []string{".txt.tmpl"},
+		ErrorTemplates: "errors/",
+	}))
+
+	// routing
+	m.Get("/html", func(r Render) {
+		r.HTML(200, "broken", struct{}{})
+	})
+	m.Get("/text", func(r Render) {
+		r.TextTemplate(200, "broken", struct{}{})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/html", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	body := res.Body.String()
+	expect(t, strings.Contains(body, "template: broken:3:"), true)
+	expect(t, strings.Contains(body, "<h2>broken</h2>"), true)
+	expect(t, strings.Contains(body, "<span>   2  &lt;p&gt;</span>"), true)
+	expect(t, strings.Contains(body, `<span class="current">   3  {{ .Missing.Field }}</span>`), true)
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/text", nil)
+	req.Header.Set("Accept", "text/html")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+	expect(t, strings.Contains(res.Body.String(), `<span class="current">   1  Broken {{ .Missing.Field }}</span>`), true)
+
+	// other clients get the error template
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/html", nil)
+	req.Header.Set("Accept", "*/*")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+	expect(t, res.Body.String(), "<h1>Sorry, something went wrong</h1>\n")
+}
+
+func Test_Render_Developer_Error_Parse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTemplate(t, filepath.Join(dir, "hello.tmpl"), "<h1>Hello</h1>\n{{ end }}\n", time.Now())
+
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: dir,
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "hello", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+	req.Header.Set("Accept", "text/html")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+	expect(t, strings.Contains(res.Body.String(), `<span class="current">   2  {{ end }}</span>`), true)
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"fmt"
+	"github.com/codegangsta/martini"
+	"reflect"
+)
+
+// GlobalKey is the key under which the results of Options.Globals are merged into map bindings.
+const GlobalKey = "Global"
+
+// validateGlobals panics unless every provider of Options.Globals is a function returning a value.
+func validateGlobals(globals map[string]martini.Handler) {
+	for name, provider := range globals {
+		if t := reflect.TypeOf(provider); t == nil || t.Kind() != reflect.Func || t.NumOut() == 0 {
+			panic(fmt.Sprintf("render: global %q must be a function returning a value", name))
+		}
+	}
+}
+
+// globals invokes the providers of Options.Globals through the injector, at most once per request, and returns
+// their results by name. A provider may return an error as its last value, which is then returned.
+func (r *renderer) globals() (map[string]interface{}, error) {
+	if r.globalData != nil {
+		return r.globalData, nil
+	}
+
+	data := map[string]interface{}{}
+	for name, provider := range r.opt.Globals {
+		vals, err := r.context.Invoke(provider)
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) > 1 {
+			if err, ok := vals[len(vals)-1].Interface().(error); ok && err != nil {
+				return nil, err
+			}
+		}
+		data[name] = vals[0].Interface()
+	}
+
+	r.globalData = data
+	return data, nil
+}
+
+// bindGlobals returns binding with the results of Options.Globals under GlobalKey, if it is nil or a
+// map[string]interface{} which doesn't have that key already. The map itself is left untouched.
+func (r *renderer) bindGlobals(binding interface{}) (interface{}, error) {
+	if len(r.opt.Globals) == 0 {
+		return binding, nil
+	}
+
+	var merged map[string]interface{}
+	switch b := binding.(type) {
+	case nil:
+		merged = map[string]interface{}{}
+	case map[string]interface{}:
+		if _, ok := b[GlobalKey]; ok {
+			return binding, nil
+		}
+		merged = make(map[string]interface{}, len(b)+1)
+		for k, v := range b {
+			merged[k] = v
+		}
+	default:
+		return binding, nil
+	}
+
+	globals, err := r.globals()
+	if err != nil {
+		return nil, err
+	}
+	merged[GlobalKey] = globals
+	return merged, nil
+}
+
+// global lets templates get the result of a provider of Options.Globals with {{ global "name" }}, whatever their
+// binding.
+func (r *renderer) global(name string) (interface{}, error) {
+	if _, ok := r.opt.Globals[name]; !ok {
+		return nil, fmt.Errorf("global %q is not defined", name)
+	}
+
+	globals, err := r.globals()
+	if err != nil {
+		return nil, err
+	}
+	return globals[name], nil
+}
@@ -0,0 +1,90 @@
+package render
+
+import (
+	"errors"
+	"github.com/codegangsta/martini"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Render_Globals(t *testing.T) {
+	count := 0
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/globals",
+		Globals: map[string]martini.Handler{
+			"User": func(req *http.Request) string {
+				return req.Header.Get("X-User")
+			},
+			"Count": func() (int, error) {
+				count++
+				return count, nil
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/map", func(r Render) {
+		r.HTML(200, "map", map[string]interface{}{"Title": "Home"})
+	})
+	m.Get("/struct", func(r Render) {
+		r.HTML(200, "struct", struct{ Title string }{"Home"})
+	})
+	m.Get("/layout", func(r Render) {
+		r.HTML(200, "map", nil, HTMLOptions{Layout: "layout"})
+	})
+	m.Get("/own", func(r Render) {
+		r.HTML(200, "map", map[string]interface{}{"Title": "Home", "Global": map[string]string{"User": "nobody"}})
+	})
+
+	bodies := map[string]string{
+		"/map":    "jeremy: Home",
+		"/struct": "jeremy: Home",
+		"/layout": "[1] jeremy:  [1]",
+		"/own":    "nobody: Home",
+	}
+	for path, body := range bodies {
+		count = 0
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-User", "jeremy")
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		expect(t, res.Body.String(), body)
+	}
+}
+
+func Test_Render_Globals_Error(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/globals",
+		Globals: map[string]martini.Handler{
+			"Fail": func() (string, error) {
+				return "", errors.New("no session")
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/fail", func(r Render) {
+		r.HTML(200, "fail", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/fail", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+}
+
+func Test_Render_Bad_Globals(t *testing.T) {
+	defer func() {
+		refute(t, recover(), nil)
+	}()
+	Renderer(Options{
+		Directory: "fixtures/globals",
+		Globals:   map[string]martini.Handler{"User": "jeremy"},
+	})
+}
@@ -0,0 +1,256 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Helpers returns a library of common template functions, to add to Options.Funcs:
+//
+//	m.Use(render.Renderer(render.Options{
+//	  Funcs: []template.FuncMap{render.Helpers()},
+//	}))
+//
+// The value a function works on comes last, so that it can be piped, as in {{ .Body | truncate 100 }}.
+//
+// Collections:
+//
+//	dict "key" value ...   a map[string]interface{} of the key and value pairs, such as to pass several values to partial
+//	list value ...         a []interface{} of the values
+//	first list             the first element of a slice or array, or nil if it is empty
+//	last list              the last element of a slice or array, or nil if it is empty
+//	join sep list          the elements of a slice or array, formatted like fmt.Sprint and separated by sep
+//	contains value list    whether a slice or array has an element equal to value, or a string has value as substring
+//
+// Strings:
+//
+//	default def value      value, or def if value is empty (nil, false, 0, "" or an empty collection)
+//	upper s, lower s, trim s
+//	truncate length s      s cut to length runes, followed by "..." if it was longer
+//	replace old new s      s with every old replaced by new
+//	split sep s            the substrings of s between each sep
+//	hasPrefix prefix s, hasSuffix suffix s
+//
+// Math, on integers:
+//
+//	add a b, sub a b, mul a b, div a b, mod a b, max a b, min a b
+//
+// Time:
+//
+//	now                    the current time
+//	date layout t          t, a time.Time or *time.Time, formatted with layout such as "2006-01-02", or "" for a zero or nil time
+//
+// Safe content, for trusted strings only, which are then not escaped by html/template:
+//
+//	safeHTML s, safeAttr s, safeURL s, safeJS s, safeCSS s
+func Helpers() template.FuncMap {
+	return template.FuncMap{
+		"dict":     makeDict,
+		"list":     makeList,
+		"first":    first,
+		"last":     last,
+		"join":     join,
+		"contains": contains,
+
+		"default":   defaultValue,
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"trim":      strings.TrimSpace,
+		"truncate":  truncate,
+		"replace":   replace,
+		"split":     split,
+		"hasPrefix": hasPrefix,
+		"hasSuffix": hasSuffix,
+
+		"add": arithmetic(func(a, b int) (int, error) { return a + b, nil }),
+		"sub": arithmetic(func(a, b int) (int, error) { return a - b, nil }),
+		"mul": arithmetic(func(a, b int) (int, error) { return a * b, nil }),
+		"div": arithmetic(func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, fmt.Errorf("div by zero")
+			}
+			return a / b, nil
+		}),
+		"mod": arithmetic(func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, fmt.Errorf("mod by zero")
+			}
+			return a % b, nil
+		}),
+		"max": arithmetic(func(a, b int) (int, error) {
+			if a > b {
+				return a, nil
+			}
+			return b, nil
+		}),
+		"min": arithmetic(func(a, b int) (int, error) {
+			if a < b {
+				return a, nil
+			}
+			return b, nil
+		}),
+
+		"now":  time.Now,
+		"date": date,
+
+		"safeHTML": func(s string) template.HTML { return template.HTML(s) },
+		"safeAttr": func(s string) template.HTMLAttr { return template.HTMLAttr(s) },
+		"safeURL":  func(s string) template.URL { return template.URL(s) },
+		"safeJS":   func(s string) template.JS { return template.JS(s) },
+		"safeCSS":  func(s string) template.CSS { return template.CSS(s) },
+	}
+}
+
+func makeDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict takes key and value pairs, got %d arguments", len(pairs))
+	}
+
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+func makeList(values ...interface{}) []interface{} {
+	return values
+}
+
+// sequence returns v if it is a slice or an array.
+func sequence(name string, v interface{}) (reflect.Value, error) {
+	s, ok := indirect(reflect.ValueOf(v))
+	if ok && (s.Kind() == reflect.Slice || s.Kind() == reflect.Array) {
+		return s, nil
+	}
+	return s, fmt.Errorf("%s takes a slice or an array, got %T", name, v)
+}
+
+func first(v interface{}) (interface{}, error) {
+	s, err := sequence("first", v)
+	if err != nil || s.Len() == 0 {
+		return nil, err
+	}
+	return s.Index(0).Interface(), nil
+}
+
+func last(v interface{}) (interface{}, error) {
+	s, err := sequence("last", v)
+	if err != nil || s.Len() == 0 {
+		return nil, err
+	}
+	return s.Index(s.Len() - 1).Interface(), nil
+}
+
+func join(sep string, v interface{}) (string, error) {
+	s, err := sequence("join", v)
+	if err != nil {
+		return "", err
+	}
+
+	elems := make([]string, s.Len())
+	for i := range elems {
+		elems[i] = fmt.Sprint(s.Index(i).Interface())
+	}
+	return strings.Join(elems, sep), nil
+}
+
+func contains(value interface{}, v interface{}) (bool, error) {
+	if str, ok := v.(string); ok {
+		return strings.Contains(str, fmt.Sprint(value)), nil
+	}
+
+	s, err := sequence("contains", v)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < s.Len(); i++ {
+		if reflect.DeepEqual(s.Index(i).Interface(), value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func defaultValue(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 {
+		return def
+	}
+	if v := reflect.ValueOf(value[0]); !v.IsValid() || isEmptyValue(v) {
+		return def
+	}
+	return value[0]
+}
+
+func truncate(length int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length]) + "..."
+}
+
+func replace(old, with, s string) string {
+	return strings.Replace(s, old, with, -1)
+}
+
+func split(sep, s string) []string {
+	return strings.Split(s, sep)
+}
+
+func hasPrefix(prefix, s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+func hasSuffix(suffix, s string) bool {
+	return strings.HasSuffix(s, suffix)
+}
+
+// arithmetic returns a template function applying op to two integers of any type.
+func arithmetic(op func(a, b int) (int, error)) func(a, b interface{}) (int, error) {
+	return func(a, b interface{}) (int, error) {
+		x, err := toInt(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := toInt(b)
+		if err != nil {
+			return 0, err
+		}
+		return op(x, y)
+	}
+}
+
+func toInt(v interface{}) (int, error) {
+	n := reflect.ValueOf(v)
+	switch n.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(n.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(n.Uint()), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", v)
+}
+
+func date(layout string, t interface{}) (string, error) {
+	switch t := t.(type) {
+	case time.Time:
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil || t.IsZero() {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("date takes a time.Time, got %T", t)
+}
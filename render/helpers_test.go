@@ -0,0 +1,81 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"time"
+)
+
+func executeHelpers(t *testing.T, src string, data interface{}) (string, error) {
+	tmpl, err := template.New("helpers").Funcs(Helpers()).Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, data)
+	return buf.String(), err
+}
+
+func Test_Helpers(t *testing.T) {
+	at := time.Date(2014, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := map[string]interface{}{
+		"Tags":  []string{"go", "martini", "web"},
+		"Empty": []int{},
+		"Name":  "",
+		"Body":  "Martini is a powerful package",
+		"At":    at,
+		"AtPtr": &at,
+		"Never": (*time.Time)(nil),
+		"Count": uint8(7),
+		"HTML":  "<b>bold</b>",
+	}
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`{{ $d := dict "a" 1 "b" "two" }}{{ $d.a }} {{ $d.b }}`, "1 two"},
+		{`{{ range list 1 "a" true }}{{ . }},{{ end }}`, "1,a,true,"},
+		{`{{ first .Tags }} {{ last .Tags }} {{ first .Empty }}`, "go web "},
+		{`{{ .Tags | join ", " }}`, "go, martini, web"},
+		{`{{ .Tags | contains "go" }} {{ .Tags | contains "rails" }} {{ .Body | contains "power" }}`, "true false true"},
+		{`{{ .Name | default "anonymous" }} {{ .Body | default "anonymous" | truncate 7 }}`, "anonymous Martini..."},
+		{`{{ .Missing | default 0 }} {{ .Empty | default "none" }} {{ default "x" }}`, "0 none x"},
+		{`{{ upper "go" }} {{ lower "GO" }} [{{ trim "  go  " }}] {{ truncate 20 "short" }}`, "GO go [go] short"},
+		{`{{ .Body | replace "powerful" "small" }} {{ index (split "," "a,b") 1 }}`, "Martini is a small package b"},
+		{`{{ .Body | hasPrefix "Martini" }} {{ .Body | hasSuffix "Martini" }}`, "true false"},
+		{`{{ add 1 2 }} {{ sub 1 2 }} {{ mul .Count 3 }} {{ div 7 2 }} {{ mod 7 2 }} {{ max 1 2 }} {{ min 1 2 }}`, "3 -1 21 3 1 2 1"},
+		{`{{ .At | date "2006-01-02" }} {{ .AtPtr | date "15:04" }} [{{ .Never | date "2006" }}]`, "2014-01-02 03:04 []"},
+		{`{{ .HTML }} {{ safeHTML .HTML }}`, "&lt;b&gt;bold&lt;/b&gt; <b>bold</b>"},
+		{`<a href="{{ "javascript:go()" }}"><a href="{{ safeURL "javascript:go()" }}">`, `<a href="#ZgotmplZ"><a href="javascript:go%28%29">`},
+	}
+
+	for _, test := range tests {
+		out, err := executeHelpers(t, test.src, data)
+		expect(t, err, nil)
+		expect(t, out, test.expected)
+	}
+
+	out, err := executeHelpers(t, `{{ now.Year }}`, nil)
+	expect(t, err, nil)
+	refute(t, out, "")
+}
+
+func Test_Helpers_Errors(t *testing.T) {
+	tests := []string{
+		`{{ dict "a" }}`,
+		`{{ dict 1 2 }}`,
+		`{{ first "go" }}`,
+		`{{ join ", " 3 }}`,
+		`{{ div 1 0 }}`,
+		`{{ mod 1 0 }}`,
+		`{{ add 1 "2" }}`,
+		`{{ date "2006" "yesterday" }}`,
+	}
+
+	for _, src := range tests {
+		_, err := executeHelpers(t, src, nil)
+		refute(t, err, nil)
+	}
+}
@@ -0,0 +1,469 @@
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentJSONAPI = "application/vnd.api+json"
+	ContentHAL     = "application/hal+json"
+)
+
+// APIError is an error object of a JSON:API document, as written by JSONAPIErrors.
+type APIError struct {
+	// ID is a unique identifier of this occurrence of the problem.
+	ID string `json:"id,omitempty"`
+	// Status is the HTTP status code, as a string. Defaults to the status of the response.
+	Status string `json:"status,omitempty"`
+	// Code is an application specific error code.
+	Code string `json:"code,omitempty"`
+	// Title is a short summary of the problem, which doesn't change between occurrences.
+	Title string `json:"title,omitempty"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Source points to the cause of the problem in the request.
+	Source *APIErrorSource `json:"source,omitempty"`
+	// Meta holds any other information about the problem.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// APIErrorSource points to the cause of an APIError in the request.
+type APIErrorSource struct {
+	// Pointer is a JSON Pointer to the offending value of the request document, such as "/data/attributes/title".
+	Pointer string `json:"pointer,omitempty"`
+	// Parameter is the offending query parameter.
+	Parameter string `json:"parameter,omitempty"`
+}
+
+type jsonAPIDocument struct {
+	Data     interface{}        `json:"data"`
+	Included []*jsonAPIResource `json:"included,omitempty"`
+}
+
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]interface{}         `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+type jsonAPIRelationship struct {
+	Data interface{} `json:"data"`
+}
+
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// JSONAPI writes v, a struct or a slice of structs, as a JSON:API document. The fields of the structs are mapped
+// by their jsonapi tags:
+//
+//	ID       int64     `jsonapi:"primary,articles"` // the id of the resource, and its type
+//	Title    string    `jsonapi:"attr,title"`       // an attribute
+//	Author   *Person   `jsonapi:"relation,author"`  // a to-one relationship, or to-many with a slice
+//	Self     string    `jsonapi:"link,self"`        // a link of the resource
+//
+// Attributes and relationships can be omitted when empty with "omitempty", such as `jsonapi:"attr,title,omitempty"`.
+// Related resources are included in the compound document, once each. Fields without a jsonapi tag are left out.
+func (r *renderer) JSONAPI(status int, v interface{}) {
+	doc, err := newJSONAPIDocument(v)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	result, err := r.marshalJSON(doc)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	// JSON:API forbids media type parameters, so there is no charset
+	r.writeBody(status, ContentJSONAPI, result)
+}
+
+// JSONAPIErrors writes the given errors as a JSON:API error document.
+func (r *renderer) JSONAPIErrors(status int, errs ...APIError) {
+	for i := range errs {
+		if len(errs[i].Status) == 0 {
+			errs[i].Status = strconv.Itoa(status)
+		}
+	}
+
+	result, err := r.marshalJSON(map[string][]APIError{"errors": errs})
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	r.writeBody(status, ContentJSONAPI, result)
+}
+
+// HAL writes v, a struct, as a HAL document. Its fields are written as properties, just like JSON would, except for
+// the ones with a hal tag:
+//
+//	Self     string    `hal:"link,self"`       // a link, or several with a slice of strings
+//	Author   *Person   `hal:"embed,author"`    // an embedded resource, or several with a slice
+//
+// Embedded resources are written as HAL documents too, and empty links or nil resources are left out.
+func (r *renderer) HAL(status int, v interface{}) {
+	doc, err := halObject(reflect.ValueOf(v))
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	result, err := r.marshalJSON(doc)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	r.writeBody(status, ContentHAL+r.compiledCharset, result)
+}
+
+// jsonAPIBuilder turns structs into JSON:API resources, collecting the related resources to include.
+type jsonAPIBuilder struct {
+	seen     map[jsonAPIIdentifier]bool
+	included []*jsonAPIResource
+}
+
+func newJSONAPIDocument(v interface{}) (*jsonAPIDocument, error) {
+	b := &jsonAPIBuilder{seen: map[jsonAPIIdentifier]bool{}}
+	doc := &jsonAPIDocument{}
+
+	val, ok := indirect(reflect.ValueOf(v))
+	if !ok {
+		return doc, nil
+	}
+
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		// primary resources are never included, even when they are related to each other
+		for i := 0; i < val.Len(); i++ {
+			id, err := jsonAPIIdentify(val.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			b.seen[id] = true
+		}
+
+		data := make([]*jsonAPIResource, val.Len())
+		for i := range data {
+			data[i] = &jsonAPIResource{}
+			if err := b.fill(data[i], val.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		doc.Data = data
+	} else {
+		id, err := jsonAPIIdentify(val)
+		if err != nil {
+			return nil, err
+		}
+		b.seen[id] = true
+
+		data := &jsonAPIResource{}
+		if err := b.fill(data, val); err != nil {
+			return nil, err
+		}
+		doc.Data = data
+	}
+
+	doc.Included = b.included
+	return doc, nil
+}
+
+// fill sets up res from the struct v, including its related resources.
+func (b *jsonAPIBuilder) fill(res *jsonAPIResource, v reflect.Value) error {
+	v, ok := indirect(v)
+	if !ok {
+		return fmt.Errorf("render: JSON:API resources can't be nil")
+	} else if v.Kind() != reflect.Struct {
+		return fmt.Errorf("render: JSON:API resources must be structs, got %s", v.Type())
+	}
+
+	primary := false
+	err := eachField(v, func(field reflect.StructField, fv reflect.Value) error {
+		kind, name, omitEmpty := parseTag(field, "jsonapi")
+		switch kind {
+		case "":
+		case "primary":
+			res.Type, res.ID = name, fmt.Sprint(fv.Interface())
+			primary = true
+		case "attr":
+			if omitEmpty && isEmptyValue(fv) {
+				return nil
+			}
+			if res.Attributes == nil {
+				res.Attributes = map[string]interface{}{}
+			}
+			res.Attributes[name] = fv.Interface()
+		case "relation":
+			if omitEmpty && isEmptyValue(fv) {
+				return nil
+			}
+			rel, err := b.relationship(fv)
+			if err != nil {
+				return err
+			}
+			if res.Relationships == nil {
+				res.Relationships = map[string]jsonAPIRelationship{}
+			}
+			res.Relationships[name] = rel
+		case "link":
+			if fv.Kind() != reflect.String {
+				return fmt.Errorf("render: JSON:API link %s of %s must be a string", field.Name, v.Type())
+			}
+			if fv.Len() == 0 {
+				return nil
+			}
+			if res.Links == nil {
+				res.Links = map[string]string{}
+			}
+			res.Links[name] = fv.String()
+		default:
+			return fmt.Errorf("render: unknown jsonapi tag %q on %s of %s", kind, field.Name, v.Type())
+		}
+		return nil
+	})
+	if err == nil && !primary {
+		err = fmt.Errorf("render: %s has no jsonapi:\"primary,type\" field", v.Type())
+	}
+	return err
+}
+
+// relationship returns the identifiers of the resources in v, a struct or a slice of structs, and includes them.
+func (b *jsonAPIBuilder) relationship(v reflect.Value) (jsonAPIRelationship, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return jsonAPIRelationship{}, nil
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		ids := make([]jsonAPIIdentifier, v.Len())
+		for i := range ids {
+			id, err := b.include(v.Index(i))
+			if err != nil {
+				return jsonAPIRelationship{}, err
+			}
+			ids[i] = id
+		}
+		return jsonAPIRelationship{ids}, nil
+	}
+
+	id, err := b.include(v)
+	return jsonAPIRelationship{id}, err
+}
+
+// include adds the resource v to the included resources, unless it already is, and returns its identifier.
+func (b *jsonAPIBuilder) include(v reflect.Value) (jsonAPIIdentifier, error) {
+	id, err := jsonAPIIdentify(v)
+	if err != nil || b.seen[id] {
+		return id, err
+	}
+	b.seen[id] = true
+
+	// add it before its own related resources, which keeps the order of the document natural
+	res := &jsonAPIResource{}
+	b.included = append(b.included, res)
+	return id, b.fill(res, v)
+}
+
+// jsonAPIIdentify returns the type and id of the resource v.
+func jsonAPIIdentify(v reflect.Value) (jsonAPIIdentifier, error) {
+	var id jsonAPIIdentifier
+	v, ok := indirect(v)
+	if !ok {
+		return id, fmt.Errorf("render: JSON:API resources can't be nil")
+	} else if v.Kind() != reflect.Struct {
+		return id, fmt.Errorf("render: JSON:API resources must be structs, got %s", v.Type())
+	}
+
+	found := false
+	eachField(v, func(field reflect.StructField, fv reflect.Value) error {
+		if kind, name, _ := parseTag(field, "jsonapi"); kind == "primary" && !found {
+			id, found = jsonAPIIdentifier{name, fmt.Sprint(fv.Interface())}, true
+		}
+		return nil
+	})
+	if !found {
+		return id, fmt.Errorf("render: %s has no jsonapi:\"primary,type\" field", v.Type())
+	}
+	return id, nil
+}
+
+type halLink struct {
+	Href string `json:"href"`
+}
+
+// halObject returns the HAL document of the struct v.
+func halObject(v reflect.Value) (map[string]interface{}, error) {
+	v, ok := indirect(v)
+	if !ok {
+		return nil, fmt.Errorf("render: HAL resources can't be nil")
+	} else if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("render: HAL resources must be structs, got %s", v.Type())
+	}
+
+	obj := map[string]interface{}{}
+	links := map[string]interface{}{}
+	embedded := map[string]interface{}{}
+	err := eachField(v, func(field reflect.StructField, fv reflect.Value) error {
+		kind, name, _ := parseTag(field, "hal")
+		switch kind {
+		case "":
+			name, omitEmpty, skip := jsonFieldName(field)
+			if !skip && !(omitEmpty && isEmptyValue(fv)) {
+				obj[name] = fv.Interface()
+			}
+		case "link":
+			if fv.Kind() == reflect.String {
+				if fv.Len() > 0 {
+					links[name] = halLink{fv.String()}
+				}
+			} else if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String {
+				hrefs := make([]halLink, fv.Len())
+				for i := range hrefs {
+					hrefs[i] = halLink{fv.Index(i).String()}
+				}
+				links[name] = hrefs
+			} else {
+				return fmt.Errorf("render: HAL link %s of %s must be a string or a slice of strings", field.Name, v.Type())
+			}
+		case "embed":
+			fv, ok := indirect(fv)
+			if !ok {
+				return nil
+			}
+			if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+				objs := make([]interface{}, fv.Len())
+				for i := range objs {
+					o, err := halObject(fv.Index(i))
+					if err != nil {
+						return err
+					}
+					objs[i] = o
+				}
+				embedded[name] = objs
+			} else {
+				o, err := halObject(fv)
+				if err != nil {
+					return err
+				}
+				embedded[name] = o
+			}
+		default:
+			return fmt.Errorf("render: unknown hal tag %q on %s of %s", kind, field.Name, v.Type())
+		}
+		return nil
+	})
+
+	if len(links) > 0 {
+		obj["_links"] = links
+	}
+	if len(embedded) > 0 {
+		obj["_embedded"] = embedded
+	}
+	return obj, err
+}
+
+// eachField calls fn for each exported field of the struct v, including the fields of untagged anonymous structs.
+func eachField(v reflect.Value, fn func(field reflect.StructField, fv reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if field.Anonymous && field.Tag == "" {
+			if ev, ok := indirect(fv); ok && ev.Kind() == reflect.Struct {
+				if err := eachField(ev, fn); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if err := fn(field, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTag returns the kind and name of a field from its tag with the given key, such as "attr" and "title" from
+// `jsonapi:"attr,title,omitempty"`. The name defaults to the name of the field.
+func parseTag(field reflect.StructField, key string) (kind, name string, omitEmpty bool) {
+	tag := field.Tag.Get(key)
+	if len(tag) == 0 {
+		return "", "", false
+	}
+
+	parts := strings.Split(tag, ",")
+	kind, name = parts[0], field.Name
+	if len(parts) > 1 && len(parts[1]) > 0 {
+		name = parts[1]
+	}
+	for i := 2; i < len(parts); i++ {
+		if parts[i] == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return kind, name, omitEmpty
+}
+
+// jsonFieldName returns the name encoding/json gives to a field, whether it is omitted when empty, and whether it is
+// skipped altogether.
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = field.Name
+	if len(parts[0]) > 0 {
+		name = parts[0]
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// indirect dereferences v through pointers and interfaces, and returns false if it is nil.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+// isEmptyValue reports whether v is empty by the rules of encoding/json's omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type apiPerson struct {
+	ID   int    `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type apiComment struct {
+	ID     string     `jsonapi:"primary,comments"`
+	Body   string     `jsonapi:"attr,body"`
+	Author *apiPerson `jsonapi:"relation,author"`
+}
+
+type apiArticle struct {
+	ID       int          `jsonapi:"primary,articles"`
+	Title    string       `jsonapi:"attr,title"`
+	Draft    bool         `jsonapi:"attr,draft,omitempty"`
+	Author   *apiPerson   `jsonapi:"relation,author"`
+	Editor   *apiPerson   `jsonapi:"relation,editor"`
+	Comments []apiComment `jsonapi:"relation,comments"`
+	Self     string       `jsonapi:"link,self"`
+	Internal string
+}
+
+type halPerson struct {
+	Name string `json:"name"`
+	Self string `hal:"link,self"`
+}
+
+type halOrder struct {
+	Total     float64     `json:"total"`
+	Currency  string      `json:"currency,omitempty"`
+	Secret    string      `json:"-"`
+	Self      string      `hal:"link,self"`
+	Next      string      `hal:"link,next"`
+	Invoices  []string    `hal:"link,invoices"`
+	Customer  *halPerson  `hal:"embed,customer"`
+	Reviewers []halPerson `hal:"embed,reviewers"`
+}
+
+func Test_Render_JSONAPI(t *testing.T) {
+	dan := &apiPerson{9, "Dan"}
+	article := apiArticle{
+		ID:     1,
+		Title:  "JSON:API",
+		Author: dan,
+		Comments: []apiComment{
+			{"5", "First!", &apiPerson{2, "Steve"}},
+			{"12", "I like XML better", dan},
+		},
+		Self:     "/articles/1",
+		Internal: "secret",
+	}
+
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/article", func(r Render) {
+		r.JSONAPI(200, &article)
+	})
+	m.Get("/people", func(r Render) {
+		r.JSONAPI(200, []*apiPerson{dan, {2, "Steve"}})
+	})
+	m.Get("/nothing", func(r Render) {
+		r.JSONAPI(200, (*apiArticle)(nil))
+	})
+	m.Get("/invalid", func(r Render) {
+		r.JSONAPI(200, Greeting{"hello", "world"})
+	})
+
+	bodies := map[string]string{
+		"/article": `{"data":{"type":"articles","id":"1","attributes":{"title":"JSON:API"},` +
+			`"relationships":{"author":{"data":{"type":"people","id":"9"}},` +
+			`"comments":{"data":[{"type":"comments","id":"5"},{"type":"comments","id":"12"}]},` +
+			`"editor":{"data":null}},"links":{"self":"/articles/1"}},` +
+			`"included":[{"type":"people","id":"9","attributes":{"name":"Dan"}},` +
+			`{"type":"comments","id":"5","attributes":{"body":"First!"},"relationships":{"author":{"data":{"type":"people","id":"2"}}}},` +
+			`{"type":"people","id":"2","attributes":{"name":"Steve"}},` +
+			`{"type":"comments","id":"12","attributes":{"body":"I like XML better"},"relationships":{"author":{"data":{"type":"people","id":"9"}}}}]}`,
+		"/people":  `{"data":[{"type":"people","id":"9","attributes":{"name":"Dan"}},{"type":"people","id":"2","attributes":{"name":"Steve"}}]}`,
+		"/nothing": `{"data":null}`,
+	}
+	for path, body := range bodies {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		expect(t, res.Header().Get(ContentType), ContentJSONAPI)
+		expect(t, res.Body.String(), body)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/invalid", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+}
+
+func Test_Render_JSONAPIErrors(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Post("/articles", func(r Render) {
+		r.JSONAPIErrors(422, APIError{
+			Title:  "Invalid title",
+			Source: &APIErrorSource{Pointer: "/data/attributes/title"},
+		}, APIError{
+			Status: "409",
+			Code:   "duplicate",
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/articles", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 422)
+	expect(t, res.Header().Get(ContentType), ContentJSONAPI)
+	expect(t, res.Body.String(), `{"errors":[{"status":"422","title":"Invalid title","source":{"pointer":"/data/attributes/title"}},{"status":"409","code":"duplicate"}]}`)
+}
+
+func Test_Render_HAL(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/order", func(r Render) {
+		r.HAL(200, halOrder{
+			Total:     30,
+			Secret:    "hidden",
+			Self:      "/orders/1",
+			Invoices:  []string{"/invoices/1"},
+			Customer:  &halPerson{"Jeremy", "/people/1"},
+			Reviewers: []halPerson{{Name: "Cory"}},
+		})
+	})
+	m.Get("/invalid", func(r Render) {
+		r.HAL(200, []halOrder{})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/order", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentHAL+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"_embedded":{"customer":{"_links":{"self":{"href":"/people/1"}},"name":"Jeremy"},"reviewers":[{"name":"Cory"}]},`+
+		`"_links":{"invoices":[{"href":"/invoices/1"}],"self":{"href":"/orders/1"}},"total":30}`)
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/invalid", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 500)
+}
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The representations Negotiate can choose from, and the media types a client may ask for them with.
+var negotiableTypes = []struct {
+	contentType string
+	mediaTypes  []string
+}{
+	{ContentHTML, []string{"text/html", "application/xhtml+xml"}},
+	{ContentJSON, []string{"application/json"}},
+	{ContentXML, []string{"text/xml", "application/xml"}},
+}
+
+// negotiableType returns the representation Negotiate writes for mediaType, such as ContentXML for "application/xml".
+func negotiableType(mediaType string) (string, bool) {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	for _, negotiable := range negotiableTypes {
+		for _, m := range negotiable.mediaTypes {
+			if m == mediaType {
+				return negotiable.contentType, true
+			}
+		}
+	}
+	return "", false
+}
+
+// mediaRange is a single entry of an Accept header.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+	position  int
+}
+
+// specificity is 2 for an exact media type, 1 for "type/*" and 0 for "*/*".
+func (m mediaRange) specificity() int {
+	if m.mediaType == "*/*" {
+		return 0
+	} else if strings.HasSuffix(m.mediaType, "/*") {
+		return 1
+	}
+	return 2
+}
+
+// compare orders media ranges by preference: by quality, then by specificity, then by their position in the header.
+func (m mediaRange) compare(o mediaRange) int {
+	switch {
+	case m.quality != o.quality:
+		if m.quality > o.quality {
+			return 1
+		}
+		return -1
+	case m.specificity() != o.specificity():
+		return m.specificity() - o.specificity()
+	}
+	return o.position - m.position
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	switch m.specificity() {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(m.mediaType, "*"))
+	}
+	return m.mediaType == mediaType
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for i, value := range strings.Split(header, ",") {
+		params := strings.Split(value, ";")
+		r := mediaRange{strings.ToLower(strings.TrimSpace(params[0])), 1, i}
+		if r.mediaType == "" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					r.quality = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
+
+// bestMatch returns the media range that decides how acceptable mediaType is: the most specific one that matches it.
+func bestMatch(ranges []mediaRange, mediaType string) (mediaRange, bool) {
+	var best mediaRange
+	found := false
+	for _, r := range ranges {
+		if r.matches(mediaType) && (!found || r.specificity() > best.specificity()) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// negotiate returns the content type to respond with, or "" if the client accepts none of them.
+func (r *renderer) negotiate(htmlAvailable bool) string {
+	def := r.opt.NegotiateDefault
+	if def == ContentHTML && !htmlAvailable {
+		def = ContentJSON
+	}
+
+	header := r.req.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return def
+	}
+	ranges := parseAccept(header)
+
+	chosen := ""
+	var chosenRange mediaRange
+	for _, negotiable := range negotiableTypes {
+		if negotiable.contentType == ContentHTML && !htmlAvailable {
+			continue
+		}
+
+		// Pick the most preferred of the media types for this representation, unless one of them is refused outright
+		var preferred mediaRange
+		found, refused := false, false
+		for _, mediaType := range negotiable.mediaTypes {
+			match, ok := bestMatch(ranges, mediaType)
+			if !ok {
+				continue
+			} else if match.quality <= 0 && match.specificity() == 2 {
+				refused = true
+			} else if !found || match.compare(preferred) > 0 {
+				preferred, found = match, true
+			}
+		}
+		if !found || refused || preferred.quality <= 0 {
+			continue
+		}
+
+		order := preferred.compare(chosenRange)
+		if chosen == "" || order > 0 || (order == 0 && negotiable.contentType == def) {
+			chosen, chosenRange = negotiable.contentType, preferred
+		}
+	}
+
+	return chosen
+}
+
+// Negotiate writes v as HTML (rendering the named template), JSON or XML, whichever the Accept header of the request
+// prefers. Ties, including "*/*", go to Options.NegotiateDefault. If the client accepts none of them, a 406 Not
+// Acceptable status is written instead. HTML is only offered when a template name is given.
+func (r *renderer) Negotiate(status int, name string, v interface{}, htmlOpt ...HTMLOptions) {
+	r.Header().Add("Vary", "Accept")
+
+	switch r.negotiate(name != "") {
+	case ContentHTML:
+		r.HTML(status, name, v, htmlOpt...)
+	case ContentJSON:
+		r.JSON(status, v)
+	case ContentXML:
+		r.XML(status, v)
+	default:
+		r.Error(http.StatusNotAcceptable)
+	}
+}
@@ -0,0 +1,99 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Render_Negotiate(t *testing.T) {
+	tests := []struct {
+		accept      string
+		template    string
+		def         string
+		status      int
+		contentType string
+	}{
+		{"", "hello", "", 200, ContentJSON},
+		{"", "hello", ContentHTML, 200, ContentHTML},
+		{"", "", ContentHTML, 200, ContentJSON},
+		{"*/*", "hello", ContentXML, 200, ContentXML},
+		{"", "hello", "application/xml", 200, ContentXML},
+		{"*/*", "hello", "application/xml", 200, ContentXML},
+		{"*/*", "hello", "application/xhtml+xml", 200, ContentHTML},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "hello", "", 200, ContentHTML},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", "", 200, ContentXML},
+		{"application/json, text/javascript, */*; q=0.01", "hello", "", 200, ContentJSON},
+		{"application/xml;q=0.5, application/json;q=0.4", "hello", "", 200, ContentXML},
+		{"text/*, application/json", "hello", "", 200, ContentJSON},
+		{"text/*", "hello", "", 200, ContentHTML},
+		{"*/*, text/html;q=0", "hello", ContentHTML, 200, ContentJSON},
+		{"image/png", "hello", "", 406, ""},
+	}
+
+	for _, test := range tests {
+		m := martini.Classic()
+		m.Use(Renderer(Options{
+			Directory:        "fixtures/basic",
+			NegotiateDefault: test.def,
+		}))
+
+		// routing
+		m.Get("/foobar", func(r Render) {
+			r.Negotiate(201, test.template, "jeremy")
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar", nil)
+		req.Header.Set("Accept", test.accept)
+
+		m.ServeHTTP(res, req)
+
+		if test.status == 200 {
+			expect(t, res.Code, 201)
+			expect(t, res.Header().Get(ContentType), test.contentType+"; charset=UTF-8")
+		} else {
+			expect(t, res.Code, test.status)
+		}
+		expect(t, res.Header().Get("Vary"), "Accept")
+	}
+}
+
+func Test_Render_Negotiate_Bad_Default(t *testing.T) {
+	defer func() {
+		expect(t, recover(), `render: NegotiateDefault "text/csv" is not an HTML, JSON or XML media type`)
+	}()
+
+	Renderer(Options{
+		Directory:        "fixtures/basic",
+		NegotiateDefault: "text/csv",
+	})
+}
+
+func Test_Render_Negotiate_Bodies(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/basic",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.Negotiate(200, "hello", "jeremy")
+	})
+
+	bodies := map[string]string{
+		"text/html":        "<h1>Hello jeremy</h1>\n",
+		"application/json": `"jeremy"`,
+		"application/xml":  "<string>jeremy</string>",
+	}
+	for accept, body := range bodies {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar", nil)
+		req.Header.Set("Accept", accept)
+
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Body.String(), body)
+	}
+}
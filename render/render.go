@@ -26,14 +26,25 @@ package render
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"github.com/codegangsta/martini"
+	"hash/fnv"
 	"html/template"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
 )
 
 const (
@@ -41,14 +52,33 @@ const (
 	ContentLength  = "Content-Length"
 	ContentJSON    = "application/json"
 	ContentHTML    = "text/html"
+	ContentXML     = "text/xml"
+	ContentJSONP   = "application/javascript"
+	ContentText    = "text/plain"
+	ContentBinary  = "application/octet-stream"
 	defaultCharset = "UTF-8"
 )
 
+// JSONP callback names must be plain (optionally dotted) JavaScript identifiers
+var jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+
 // Included helper functions for use when rendering html
 var helperFuncs = template.FuncMap{
-	"yield": func() (string, error) {
+	"yield": func(section ...string) (string, error) {
 		return "", fmt.Errorf("yield called with no layout defined")
 	},
+	"partial": func(name string, data ...interface{}) (string, error) {
+		return "", fmt.Errorf("partial called outside of HTML rendering")
+	},
+	"cache": func(key string, ttl interface{}, name string, data ...interface{}) (string, error) {
+		return "", fmt.Errorf("cache called outside of HTML rendering")
+	},
+	"global": func(name string) (string, error) {
+		return "", fmt.Errorf("global called outside of HTML rendering")
+	},
+	"urlfor": func(name string, params ...interface{}) (string, error) {
+		return "", fmt.Errorf("urlfor called outside of HTML rendering")
+	},
 }
 
 // Render is a service that can be injected into a Martini handler. Render provides functions for easily writing JSON and
@@ -56,12 +86,39 @@ var helperFuncs = template.FuncMap{
 type Render interface {
 	// JSON writes the given status and JSON serialized version of the given value to the http.ResponseWriter.
 	JSON(status int, v interface{})
+	// JSONStream writes the elements received from a channel, or yielded by an Iterator, as a JSON array, flushing each of them to the client in turn.
+	JSONStream(status int, v interface{})
+	// JSONP writes the given value as JSON wrapped in the JavaScript callback named by the callbackParam query parameter, or as plain JSON if there is none.
+	JSONP(status int, callbackParam string, v interface{})
 	// HTML renders a html template specified by the name and writes the result and given status to the http.ResponseWriter.
 	HTML(status int, name string, v interface{}, htmlOpt ...HTMLOptions)
-	// Error is a convenience function that writes an http status to the http.ResponseWriter.
+	// XML writes the given status and XML serialized version of the given value to the http.ResponseWriter.
+	XML(status int, v interface{})
+	// Text writes the given status and plain text to the http.ResponseWriter.
+	Text(status int, v string)
+	// TextTemplate renders a text template specified by the name, without any HTML escaping, and writes the result and given status to the http.ResponseWriter as plain text.
+	TextTemplate(status int, name string, v interface{})
+	// HTMLString renders a html template like HTML, but returns the result instead of writing it to the http.ResponseWriter.
+	HTMLString(name string, v interface{}, htmlOpt ...HTMLOptions) (string, error)
+	// TextString renders a text template like TextTemplate, but returns the result instead of writing it to the http.ResponseWriter.
+	TextString(name string, v interface{}) (string, error)
+	// Data writes the given status and raw bytes to the http.ResponseWriter, as application/octet-stream unless a Content-Type was already set.
+	Data(status int, v []byte)
+	// JSONAPI writes the given status and a struct, or a slice of structs, as a JSON:API document, according to their jsonapi tags.
+	JSONAPI(status int, v interface{})
+	// JSONAPIErrors writes the given status and errors as a JSON:API error document.
+	JSONAPIErrors(status int, errs ...APIError)
+	// HAL writes the given status and struct as a HAL document, according to its hal tags.
+	HAL(status int, v interface{})
+	// Negotiate writes the given value as HTML (using the named template), JSON or XML, according to the Accept header of the request.
+	Negotiate(status int, name string, v interface{}, htmlOpt ...HTMLOptions)
+	// Error is a convenience function that writes an http status to the http.ResponseWriter, using the error template for that status if there is one.
 	Error(status int)
 	// Redirect is a convienience function that sends an HTTP redirect. If status is omitted, uses 302 (Found)
 	Redirect(location string, status ...int)
+	// LastModified sets the Last-Modified time of the HTML, JSON or XML response written next. A 200 OK is then answered
+	// with 304 Not Modified when the If-Modified-Since header of the request is not older.
+	LastModified(t time.Time)
 	// Template returns the internal *template.Template used to render the HTML
 	Template() *template.Template
 }
@@ -76,45 +133,139 @@ type Delims struct {
 
 // Options is a struct for specifying configuration options for the render.Renderer middleware
 type Options struct {
-	// Directory to load templates. Default is "templates"
+	// Directory to load templates. Default is "templates", or "/" when a FileSystem or Bundle is given.
 	Directory string
+	// Directories to load templates from, in order: a template overrides the one with the same name in an earlier
+	// directory. Overrides Directory.
+	Directories []string
+	// Namespaces maps a namespace to the directory of its templates, which are named "namespace:name", such as
+	// "mail:welcome". Defaults to nil.
+	Namespaces map[string]string
+	// Themes maps a theme name to a directory of templates overriding the ones with the same name, for the requests
+	// using that theme. Defaults to nil.
+	Themes map[string]string
+	// ThemeSelector is a handler returning the name of the theme for the current request, such as
+	// func(req *http.Request) string. Its arguments are injected like any handler, when the request first renders a
+	// template, so they may be mapped by handlers after Renderer. Defaults to nil, which uses no theme.
+	ThemeSelector martini.Handler
+	// FileSystem to load templates from instead of the local disk, such as templates bundled into the binary.
+	// Templates are looked up in its Directory and named just like on disk. Defaults to nil.
+	FileSystem http.FileSystem
+	// Bundle of templates to load from memory instead of the local disk or FileSystem, as generated by render-bundle.
+	// Directories are looked up within it. Defaults to nil.
+	Bundle Bundle
 	// Layout template name. Will not render a layout if "". Defaults to "".
 	Layout string
+	// Layout template names for nested layouts, innermost first, such as []string{"admin/layout", "layout"}. Overrides Layout.
+	Layouts []string
 	// Extensions to parse template files from. Defaults to [".tmpl"]
 	Extensions []string
+	// TextExtensions to parse text templates from, such as [".txt.tmpl"], which are rendered without HTML escaping. When
+	// a file matches both Extensions and TextExtensions, the longest extension wins. Defaults to [], for no text templates.
+	TextExtensions []string
 	// Funcs is a slice of FuncMaps to apply to the template upon compilation. This is useful for helper functions. Defaults to [].
 	Funcs []template.FuncMap
 	// Delims sets the action delimiters to the specified strings in the Delims struct.
 	Delims Delims
 	// Appends the given charset to the Content-Type header. Default is "UTF-8".
 	Charset string
+	// AssetManifest is a JSON file mapping the paths of static files to their fingerprinted names, such as written by
+	// render-assets, for the asset template function. Defaults to "".
+	AssetManifest string
+	// AssetDirectory is a directory of static files to fingerprint at startup for the asset template function, when
+	// there is no AssetManifest. Defaults to "".
+	AssetDirectory string
+	// AssetPrefix is the URL prefix of the asset template function. Default is "/".
+	AssetPrefix string
+	// Globals maps names to data provider functions, whose arguments are injected like any handler. Their results are
+	// merged into the map bindings of HTML under the "Global" key, and returned by the global template function.
+	// Defaults to nil.
+	Globals map[string]martini.Handler
+	// FragmentCache stores the fragments rendered by the cache template function. Defaults to an in-memory LRU cache
+	// of DefaultFragmentCacheSize fragments.
+	FragmentCache FragmentCache
+	// ETag emits a strong ETag header for HTML, JSON and XML responses, and answers a 200 OK with 304 Not Modified
+	// when it matches the If-None-Match header of the request. Defaults to false.
+	ETag bool
+	// ErrorTemplates is the prefix of the error templates, such as "errors/" for "errors/404" and "errors/500". Error
+	// renders the template for its status if there is one, and so do render failures with the 500 one. Defaults to "",
+	// which renders no error templates.
+	ErrorTemplates string
 	// Outputs human readable JSON
 	IndentJSON bool
+	// Outputs human readable XML
+	IndentXML bool
+	// Media type used by Negotiate when the client accepts anything equally, such as "text/html" or "application/xml".
+	// Other values panic. Default is "application/json".
+	NegotiateDefault string
 }
 
 // HTMLOptions is a struct for overriding some rendering Options for specific HTML call
 type HTMLOptions struct {
 	// Layout template name. Overrides Options.Layout.
 	Layout string
+	// Layout template names for nested layouts, innermost first. Overrides Options.Layouts and Layout.
+	Layouts []string
+}
+
+// layouts returns the layouts to wrap the page in, innermost first.
+func (opt HTMLOptions) layouts() []string {
+	if len(opt.Layouts) > 0 {
+		return opt.Layouts
+	}
+	if len(opt.Layout) > 0 {
+		return []string{opt.Layout}
+	}
+	return nil
 }
 
 // Renderer is a Middleware that maps a render.Render service into the Martini handler chain. An single variadic render.Options
 // struct can be optionally provided to configure HTML rendering. The default directory for templates is "templates" and the default
 // file extension is ".tmpl".
 //
-// If MARTINI_ENV is set to "" or "development" then templates will be recompiled on every request. For more performance, set the
-// MARTINI_ENV environment variable to "production"
+// If MARTINI_ENV is set to "" or "development" then templates will be recompiled whenever a template file is added, removed
+// or modified, and template errors are shown in the response. For more performance, set the MARTINI_ENV environment
+// variable to "production", in which case a template error panics at startup.
 func Renderer(options ...Options) martini.Handler {
 	opt := prepareOptions(options)
 	cs := prepareCharset(opt.Charset)
-	t := compile(opt)
+	asset, err := assetFunc(opt)
+	if err != nil {
+		// a missing or broken manifest would break every page
+		panic(err)
+	}
+	ts := newTemplateSet(opt, template.FuncMap{"asset": asset})
+	fragments := opt.FragmentCache
+	if fragments == nil {
+		fragments = NewLRUCache(DefaultFragmentCacheSize)
+	}
+	if err := ts.err(); err != nil && martini.Env != martini.Dev {
+		// Bomb out if parse fails. We don't want any silent server starts.
+		panic(err)
+	}
+	if opt.ThemeSelector != nil {
+		if t := reflect.TypeOf(opt.ThemeSelector); t.Kind() != reflect.Func || t.NumOut() == 0 || t.Out(0).Kind() != reflect.String {
+			panic("render: ThemeSelector must be a function returning a string")
+		}
+	}
+	validateGlobals(opt.Globals)
+
 	return func(res http.ResponseWriter, req *http.Request, c martini.Context) {
-		// recompile for easy development
-		if martini.Env == martini.Dev {
-			t = compile(opt)
+		// recompile for easy development, dropping the fragments of the old templates
+		if martini.Env == martini.Dev && ts.reload() {
+			fragments.DeletePrefix("")
 		}
-		tc, _ := t.Clone()
-		c.MapTo(&renderer{res, req, tc, opt, cs}, (*Render)(nil))
+
+		c.MapTo(&renderer{
+			ResponseWriter:  res,
+			req:             req,
+			templates:       ts,
+			context:         c,
+			fragments:       fragments,
+			opt:             opt,
+			compiledCharset: cs,
+		}, (*Render)(nil))
+		c.MapTo(fragments, (*FragmentCache)(nil))
 	}
 }
 
@@ -133,108 +284,552 @@ func prepareOptions(options []Options) Options {
 	}
 
 	// Defaults
+	if len(opt.Directory) == 0 && (opt.FileSystem != nil || opt.Bundle != nil) {
+		opt.Directory = "/"
+	}
 	if len(opt.Directory) == 0 {
 		opt.Directory = "templates"
 	}
 	if len(opt.Extensions) == 0 {
 		opt.Extensions = []string{".tmpl"}
 	}
+	if len(opt.AssetPrefix) == 0 {
+		opt.AssetPrefix = "/"
+	}
+	if len(opt.NegotiateDefault) == 0 {
+		opt.NegotiateDefault = ContentJSON
+	} else if contentType, ok := negotiableType(opt.NegotiateDefault); ok {
+		opt.NegotiateDefault = contentType
+	} else {
+		panic(fmt.Sprintf("render: NegotiateDefault %q is not an HTML, JSON or XML media type", opt.NegotiateDefault))
+	}
 
 	return opt
 }
 
-func compile(options Options) *template.Template {
-	dir := options.Directory
-	t := template.New(dir)
+// compiled holds the HTML and text templates of a theme, along with their sources for the developer error page.
+type compiled struct {
+	html    *template.Template
+	text    *texttemplate.Template
+	sources map[templateSource]string
+}
+
+// templateSource identifies the source of a template, since HTML and text templates may share a name.
+type templateSource struct {
+	name string
+	text bool
+}
+
+// compile parses the templates of the given theme, with the given built in funcs.
+func compile(options Options, theme string, builtins template.FuncMap) (*compiled, error) {
+	t := template.New(options.Directory)
 	t.Delims(options.Delims.Left, options.Delims.Right)
 	// parse an initial template in case we don't have any
 	template.Must(t.Parse("Martini"))
+	tt := texttemplate.New(options.Directory)
+	tt.Delims(options.Delims.Left, options.Delims.Right)
+	c := &compiled{t, tt, map[templateSource]string{}}
+
+	// collect the sources first, so that a template overridden by a later root is never parsed
+	var sources []templateSource
+	for _, root := range templateRoots(options, theme) {
+		err := walkTemplates(options, root.dir, func(r string, info os.FileInfo, read func() ([]byte, error)) error {
+			name, text, ok := templateName(options, r)
+			if !ok {
+				return nil
+			}
 
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		r, err := filepath.Rel(dir, path)
+			buf, err := read()
+			if err != nil {
+				return err
+			}
+
+			s := templateSource{root.prefix + name, text}
+			if _, ok := c.sources[s]; !ok {
+				sources = append(sources, s)
+			}
+			c.sources[s] = string(buf)
+			return nil
+		})
 		if err != nil {
-			return err
+			return c, err
 		}
+	}
 
-		ext := filepath.Ext(r)
-		for _, extension := range options.Extensions {
-			if ext == extension {
+	for _, s := range sources {
+		var err error
+		if s.text {
+			// built in funcs come first, so that the ones of the application take precedence
+			tmpl := tt.New(s.name).Funcs(texttemplate.FuncMap(builtins))
+			for _, funcs := range options.Funcs {
+				tmpl.Funcs(texttemplate.FuncMap(funcs))
+			}
+			_, err = tmpl.Parse(c.sources[s])
+		} else {
+			tmpl := t.New(s.name).Funcs(builtins).Funcs(helperFuncs)
+			// add our funcmaps, which take precedence over the helpers except for yield
+			for _, funcs := range options.Funcs {
+				tmpl.Funcs(funcs)
+			}
+			_, err = tmpl.Funcs(template.FuncMap{"yield": helperFuncs["yield"]}).Parse(c.sources[s])
+		}
+		if err != nil {
+			return c, err
+		}
+	}
 
-				buf, err := ioutil.ReadFile(path)
-				if err != nil {
-					panic(err)
-				}
+	return c, nil
+}
 
-				name := (r[0 : len(r)-len(ext)])
-				tmpl := t.New(filepath.ToSlash(name))
+// templateName returns the name of the template in the file at path r, and whether it is a text template, if r has one
+// of the Extensions or TextExtensions. The longest matching extension wins, so that with TextExtensions [".txt.tmpl"]
+// "welcome.txt.tmpl" is the text template "welcome" rather than the HTML template "welcome.txt".
+func templateName(options Options, r string) (name string, text bool, ok bool) {
+	match := ""
+	for _, extension := range options.Extensions {
+		if strings.HasSuffix(r, extension) && len(extension) > len(match) {
+			match, text = extension, false
+		}
+	}
+	for _, extension := range options.TextExtensions {
+		if strings.HasSuffix(r, extension) && len(extension) > len(match) {
+			match, text = extension, true
+		}
+	}
+	if len(match) == 0 {
+		return "", false, false
+	}
 
-				// add our funcmaps
-				for _, funcs := range options.Funcs {
-					tmpl.Funcs(funcs)
-				}
+	return filepath.ToSlash(r[0 : len(r)-len(match)]), text, true
+}
+
+// templateRoot is a directory to load templates from, with the prefix given to the names of its templates.
+type templateRoot struct {
+	dir    string
+	prefix string
+}
 
-				// Bomb out if parse fails. We don't want any silent server starts.
-				template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
-				break
+// templateRoots returns the directories to load the templates of the given theme from, in order: a template in a
+// later root overrides the one with the same name in an earlier root.
+func templateRoots(options Options, theme string) []templateRoot {
+	dirs := options.Directories
+	if len(dirs) == 0 {
+		dirs = []string{options.Directory}
+	}
+
+	roots := make([]templateRoot, 0, len(dirs)+len(options.Namespaces)+1)
+	for _, dir := range dirs {
+		roots = append(roots, templateRoot{dir: dir})
+	}
+
+	namespaces := make([]string, 0, len(options.Namespaces))
+	for namespace := range options.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		roots = append(roots, templateRoot{dir: options.Namespaces[namespace], prefix: namespace + ":"})
+	}
+
+	if dir, ok := options.Themes[theme]; ok && len(theme) > 0 {
+		roots = append(roots, templateRoot{dir: dir})
+	}
+
+	return roots
+}
+
+// themeNames returns the sorted names of the themes, starting with "" for no theme.
+func themeNames(options Options) []string {
+	names := make([]string, 0, len(options.Themes)+1)
+	for name := range options.Themes {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{""}, names...)
+}
+
+// fingerprint sums up the path, size and modification time of every template file, so that changes can be detected
+// without parsing anything.
+func fingerprint(options Options) uint64 {
+	h := fnv.New64a()
+	roots := templateRoots(options, "")
+	for _, theme := range themeNames(options)[1:] {
+		roots = append(roots, templateRoot{dir: options.Themes[theme]})
+	}
+
+	for _, root := range roots {
+		walkTemplates(options, root.dir, func(r string, info os.FileInfo, read func() ([]byte, error)) error {
+			if info != nil && !info.IsDir() {
+				fmt.Fprintf(h, "%s:%s:%d:%d\n", root.dir, r, info.Size(), info.ModTime().UnixNano())
 			}
+			return nil
+		})
+	}
+	return h.Sum64()
+}
+
+// templateSet holds the compiled templates of every theme, shared by all requests. Templates are only recompiled by
+// reload, and only when their files have changed.
+type templateSet struct {
+	sync.RWMutex
+	opt         Options
+	builtins    template.FuncMap
+	themes      map[string]*compiled
+	errs        map[string]error
+	fingerprint uint64
+}
+
+func newTemplateSet(opt Options, builtins template.FuncMap) *templateSet {
+	s := &templateSet{opt: opt, builtins: builtins, fingerprint: fingerprint(opt)}
+	s.compile()
+	return s
+}
+
+// compile compiles the templates of every theme. The caller must hold the write lock, if the set is shared.
+func (s *templateSet) compile() {
+	s.themes = map[string]*compiled{}
+	s.errs = map[string]error{}
+	for _, theme := range themeNames(s.opt) {
+		s.themes[theme], s.errs[theme] = compile(s.opt, theme, s.builtins)
+	}
+}
+
+// get returns the compiled templates of the given theme, and the error that prevented their compilation if any.
+// Unknown themes get the templates of no theme.
+func (s *templateSet) get(theme string) (*compiled, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if _, ok := s.themes[theme]; !ok {
+		theme = ""
+	}
+	return s.themes[theme], s.errs[theme]
+}
+
+// err returns the first error that prevented the compilation of a theme, if any.
+func (s *templateSet) err() error {
+	s.RLock()
+	defer s.RUnlock()
+	for _, theme := range themeNames(s.opt) {
+		if s.errs[theme] != nil {
+			return s.errs[theme]
 		}
+	}
+	return nil
+}
 
+// reload recompiles the templates if any template file was added, removed or modified since they were last compiled.
+// It reports whether it recompiled them.
+func (s *templateSet) reload() bool {
+	current := fingerprint(s.opt)
+
+	s.RLock()
+	changed := current != s.fingerprint
+	s.RUnlock()
+	if !changed {
+		return false
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// another request may have recompiled them in the meantime
+	if current != s.fingerprint {
+		s.compile()
+		s.fingerprint = current
+		return true
+	}
+	return false
+}
+
+// walkTemplates calls fn for every file found under dir, either in Options.Bundle, Options.FileSystem or on the
+// local disk, with its path relative to dir and a function to read its contents. Bundled files have no os.FileInfo.
+func walkTemplates(options Options, dir string, fn func(r string, info os.FileInfo, read func() ([]byte, error)) error) error {
+	if options.Bundle != nil {
+		root := path.Clean("/" + filepath.ToSlash(dir))
+		names := make([]string, 0, len(options.Bundle))
+		for name := range options.Bundle {
+			if p := path.Clean("/" + name); root == "/" || strings.HasPrefix(p, root+"/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			content := options.Bundle[name]
+			r := strings.TrimPrefix(strings.TrimPrefix(path.Clean("/"+name), root), "/")
+			err := fn(filepath.FromSlash(r), nil, func() ([]byte, error) {
+				return []byte(content), nil
+			})
+			if err != nil {
+				return err
+			}
+		}
 		return nil
+	}
+
+	if options.FileSystem != nil {
+		root := path.Clean("/" + filepath.ToSlash(dir))
+		err := walkFileSystem(options.FileSystem, root, func(p string, info os.FileInfo) error {
+			r := filepath.FromSlash(strings.TrimPrefix(strings.TrimPrefix(p, root), "/"))
+			return fn(r, info, func() ([]byte, error) {
+				f, err := options.FileSystem.Open(p)
+				if err != nil {
+					return nil, err
+				}
+				defer f.Close()
+				return ioutil.ReadAll(f)
+			})
+		})
+		if os.IsNotExist(err) {
+			// like on disk, a missing directory simply has no templates
+			return nil
+		}
+		return err
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		r, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		return fn(r, info, func() ([]byte, error) {
+			return ioutil.ReadFile(path)
+		})
 	})
+}
+
+// walkFileSystem calls fn for every file below dir in fs, in lexical order like filepath.Walk.
+func walkFileSystem(fs http.FileSystem, dir string, fn func(p string, info os.FileInfo) error) error {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(byName(infos))
+	for _, info := range infos {
+		p := path.Join(dir, info.Name())
+		if info.IsDir() {
+			err = walkFileSystem(fs, p, fn)
+		} else {
+			err = fn(p, info)
+		}
+		if err != nil {
+			return err
+		}
+	}
 
-	return t
+	return nil
 }
 
+type byName []os.FileInfo
+
+func (f byName) Len() int           { return len(f) }
+func (f byName) Less(i, j int) bool { return f[i].Name() < f[j].Name() }
+func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
 type renderer struct {
 	http.ResponseWriter
 	req             *http.Request
+	templates       *templateSet
+	loaded          bool
 	t               *template.Template
+	text            *texttemplate.Template
+	sources         map[templateSource]string
+	templateErr     error
+	theme           string
+	context         martini.Context
+	globalData      map[string]interface{}
+	fragments       FragmentCache
 	opt             Options
 	compiledCharset string
+	lastModified    time.Time
 }
 
 func (r *renderer) JSON(status int, v interface{}) {
+	result, err := r.marshalJSON(v)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	// json rendered fine, write out the result
+	r.writeBody(status, ContentJSON+r.compiledCharset, result)
+}
+
+func (r *renderer) JSONP(status int, callbackParam string, v interface{}) {
+	callback := r.req.URL.Query().Get(callbackParam)
+	if len(callback) == 0 {
+		r.JSON(status, v)
+		return
+	}
+	if len(callback) > 128 || !jsonpCallback.MatchString(callback) {
+		http.Error(r, "invalid JSONP callback name", http.StatusBadRequest)
+		return
+	}
+
+	result, err := r.marshalJSON(v)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+
+	// The leading comment keeps the response from being read as anything but a script, see CVE-2014-4671
+	r.Header().Set(ContentType, ContentJSONP+r.compiledCharset)
+	r.Header().Set("X-Content-Type-Options", "nosniff")
+	r.WriteHeader(status)
+	r.Write([]byte("/**/" + callback + "("))
+	r.Write(result)
+	r.Write([]byte(");"))
+}
+
+func (r *renderer) marshalJSON(v interface{}) ([]byte, error) {
+	if r.opt.IndentJSON {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return json.Marshal(v)
+}
+
+func (r *renderer) XML(status int, v interface{}) {
 	var result []byte
 	var err error
-	if r.opt.IndentJSON {
-		result, err = json.MarshalIndent(v, "", "  ")
+	if r.opt.IndentXML {
+		result, err = xml.MarshalIndent(v, "", "  ")
 	} else {
-		result, err = json.Marshal(v)
+		result, err = xml.Marshal(v)
 	}
 	if err != nil {
-		http.Error(r, err.Error(), 500)
+		r.renderFailed(err, false)
 		return
 	}
 
-	// json rendered fine, write out the result
-	r.Header().Set(ContentType, ContentJSON+r.compiledCharset)
+	// XML rendered fine, write out the result
+	r.writeBody(status, ContentXML+r.compiledCharset, result)
+}
+
+func (r *renderer) Text(status int, v string) {
+	r.Header().Set(ContentType, ContentText+r.compiledCharset)
 	r.WriteHeader(status)
-	r.Write(result)
+	r.Write([]byte(v))
+}
+
+func (r *renderer) Data(status int, v []byte) {
+	if r.Header().Get(ContentType) == "" {
+		r.Header().Set(ContentType, ContentBinary)
+	}
+	r.Header().Set(ContentLength, strconv.Itoa(len(v)))
+	r.WriteHeader(status)
+	r.Write(v)
 }
 
 func (r *renderer) HTML(status int, name string, binding interface{}, htmlOpt ...HTMLOptions) {
-	opt := r.prepareHTMLOptions(htmlOpt)
-	// assign a layout if there is one
-	if len(opt.Layout) > 0 {
-		r.addYield(name, binding)
-		name = opt.Layout
+	out, err := r.renderHTML(name, binding, htmlOpt)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
 	}
 
-	out, err := r.execute(name, binding)
+	// template rendered fine, write out the result
+	r.writeBody(status, ContentHTML+r.compiledCharset, out.Bytes())
+}
+
+func (r *renderer) HTMLString(name string, binding interface{}, htmlOpt ...HTMLOptions) (string, error) {
+	out, err := r.renderHTML(name, binding, htmlOpt)
 	if err != nil {
-		http.Error(r, err.Error(), http.StatusInternalServerError)
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (r *renderer) TextTemplate(status int, name string, binding interface{}) {
+	out, err := r.renderText(name, binding)
+	if err != nil {
+		r.renderFailed(err, true)
 		return
 	}
 
 	// template rendered fine, write out the result
-	r.Header().Set(ContentType, ContentHTML+r.compiledCharset)
+	r.Header().Set(ContentType, ContentText+r.compiledCharset)
 	r.WriteHeader(status)
 	io.Copy(r, out)
 }
 
-// Error writes the given HTTP status to the current ResponseWriter
-func (r *renderer) Error(status int) {
-	r.WriteHeader(status)
+func (r *renderer) TextString(name string, binding interface{}) (string, error) {
+	out, err := r.renderText(name, binding)
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// load selects the theme of the request and clones its templates, the first time they are needed.
+func (r *renderer) load() {
+	if r.loaded {
+		return
+	}
+	r.loaded = true
+
+	if r.opt.ThemeSelector != nil {
+		vals, err := r.context.Invoke(r.opt.ThemeSelector)
+		if err != nil {
+			panic(err)
+		}
+		r.theme = vals[0].String()
+	}
+
+	c, err := r.templates.get(r.theme)
+	if err == nil {
+		r.t, err = c.html.Clone()
+	}
+	r.text, r.sources, r.templateErr = c.text, c.sources, err
+}
+
+// renderHTML executes the named html template and wraps it in its layouts.
+func (r *renderer) renderHTML(name string, binding interface{}, htmlOpt []HTMLOptions) (*bytes.Buffer, error) {
+	r.load()
+	if r.templateErr != nil {
+		return nil, r.templateErr
+	}
+
+	binding, err := r.bindGlobals(binding)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := r.prepareHTMLOptions(htmlOpt)
+	r.addHelpers()
+
+	out, err := r.execute(name, binding)
+
+	// wrap the result in each layout in turn, innermost first
+	inner := []string{name}
+	for _, layout := range opt.layouts() {
+		if err != nil {
+			break
+		}
+		r.addYield(inner, template.HTML(out.String()), binding)
+		out, err = r.execute(layout, binding)
+		inner = append(inner, layout)
+	}
+
+	return out, err
+}
+
+// renderText executes the named text template.
+func (r *renderer) renderText(name string, binding interface{}) (*bytes.Buffer, error) {
+	r.load()
+	if r.templateErr != nil {
+		return nil, r.templateErr
+	}
+
+	buf := new(bytes.Buffer)
+	return buf, r.text.ExecuteTemplate(buf, name, binding)
 }
 
 func (r *renderer) Redirect(location string, status ...int) {
@@ -247,6 +842,7 @@ func (r *renderer) Redirect(location string, status ...int) {
 }
 
 func (r *renderer) Template() *template.Template {
+	r.load()
 	return r.t
 }
 
@@ -255,23 +851,73 @@ func (r *renderer) execute(name string, binding interface{}) (*bytes.Buffer, err
 	return buf, r.t.ExecuteTemplate(buf, name, binding)
 }
 
-func (r *renderer) addYield(name string, binding interface{}) {
+// addYield lets a layout output the already rendered content of the templates it wraps with {{ yield }}, and named
+// sections with {{ yield "section" }}. A section is the template "section-name" for the innermost of the wrapped
+// templates that defines one, the default template "section" otherwise, or nothing at all.
+func (r *renderer) addYield(inner []string, content template.HTML, binding interface{}) {
 	funcs := template.FuncMap{
-		"yield": func() (template.HTML, error) {
-			buf, err := r.execute(name, binding)
-			// return safe html here since we are rendering our own template
-			return template.HTML(buf.String()), err
+		"yield": func(section ...string) (template.HTML, error) {
+			if len(section) == 0 {
+				return content, nil
+			} else if len(section) > 1 {
+				return "", fmt.Errorf("yield takes at most one section name, got %d", len(section))
+			}
+
+			candidates := []string{}
+			for _, name := range inner {
+				candidates = append(candidates, section[0]+"-"+name)
+			}
+			for _, candidate := range append(candidates, section[0]) {
+				if r.t.Lookup(candidate) != nil {
+					buf, err := r.execute(candidate, binding)
+					// return safe html here since we are rendering our own template
+					return template.HTML(buf.String()), err
+				}
+			}
+
+			return "", nil
 		},
 	}
 	r.t.Funcs(funcs)
 }
 
+// addHelpers binds the helpers of helperFuncs, but yield, to this request. The helpers the application defines in
+// Options.Funcs are left alone, since those take precedence.
+func (r *renderer) addHelpers() {
+	funcs := template.FuncMap{
+		"partial": r.partial,
+		"cache":   r.cache,
+		"global":  r.global,
+		"urlfor":  r.urlFor,
+	}
+	for _, appFuncs := range r.opt.Funcs {
+		for name := range appFuncs {
+			delete(funcs, name)
+		}
+	}
+	r.t.Funcs(funcs)
+}
+
+// partial lets templates render another template with its own data using {{ partial "name" data }}.
+func (r *renderer) partial(name string, data ...interface{}) (template.HTML, error) {
+	var binding interface{}
+	if len(data) == 1 {
+		binding = data[0]
+	} else if len(data) > 1 {
+		return "", fmt.Errorf("partial takes at most one data argument, got %d", len(data))
+	}
+
+	buf, err := r.execute(name, binding)
+	return template.HTML(buf.String()), err
+}
+
 func (r *renderer) prepareHTMLOptions(htmlOpt []HTMLOptions) HTMLOptions {
 	if len(htmlOpt) > 0 {
 		return htmlOpt[0]
 	}
 
 	return HTMLOptions{
-		Layout: r.opt.Layout,
+		Layout:  r.opt.Layout,
+		Layouts: r.opt.Layouts,
 	}
 }
@@ -1,13 +1,20 @@
 package render
 
 import (
+	"encoding/xml"
 	"github.com/codegangsta/martini"
 	"html/template"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type Greeting struct {
@@ -60,6 +67,166 @@ func Test_Render_Indented_JSON(t *testing.T) {
 }`)
 }
 
+func Test_Render_JSONP(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.JSONP(300, "callback", Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar?callback=jQuery.handlers.cb_123", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 300)
+	expect(t, res.Header().Get(ContentType), ContentJSONP+"; charset=UTF-8")
+	expect(t, res.Header().Get("X-Content-Type-Options"), "nosniff")
+	expect(t, res.Body.String(), `/**/jQuery.handlers.cb_123({"one":"hello","two":"world"});`)
+}
+
+func Test_Render_JSONP_Without_Callback(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.JSONP(300, "callback", Greeting{"hello", "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 300)
+	expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+	expect(t, res.Body.String(), `{"one":"hello","two":"world"}`)
+}
+
+func Test_Render_JSONP_Bad_Callback(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.JSONP(300, "callback", Greeting{"hello", "world"})
+	})
+
+	for _, callback := range []string{"alert(1)", "cb%0A", "1cb", "cb..x", "<script>"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar?callback="+callback, nil)
+
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 400)
+		refute(t, res.Header().Get(ContentType), ContentJSONP+"; charset=UTF-8")
+	}
+}
+
+type GreetingXML struct {
+	XMLName xml.Name `xml:"greeting"`
+	One     string   `xml:"one,attr"`
+	Two     string   `xml:"two"`
+}
+
+func Test_Render_XML(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.XML(300, GreetingXML{One: "hello", Two: "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 300)
+	expect(t, res.Header().Get(ContentType), ContentXML+"; charset=UTF-8")
+	expect(t, res.Body.String(), `<greeting one="hello"><two>world</two></greeting>`)
+}
+
+func Test_Render_Indented_XML(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		IndentXML: true,
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.XML(300, GreetingXML{One: "hello", Two: "world"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 300)
+	expect(t, res.Header().Get(ContentType), ContentXML+"; charset=UTF-8")
+	expect(t, res.Body.String(), `<greeting one="hello">
+  <two>world</two>
+</greeting>`)
+}
+
+func Test_Render_Text(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Charset: "ISO-8859-1",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.Text(200, "hello world")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentText+"; charset=ISO-8859-1")
+	expect(t, res.Body.String(), "hello world")
+}
+
+func Test_Render_Data(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.Data(200, []byte("hello world"))
+	})
+	m.Get("/image", func(res http.ResponseWriter, r Render) {
+		res.Header().Set(ContentType, "image/png")
+		r.Data(200, []byte("\x89PNG"))
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentBinary)
+	expect(t, res.Header().Get(ContentLength), "11")
+	expect(t, res.Body.String(), "hello world")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/image", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Header().Get(ContentType), "image/png")
+	expect(t, res.Header().Get(ContentLength), "4")
+}
+
 func Test_Render_Bad_HTML(t *testing.T) {
 	m := martini.Classic()
 	m.Use(Renderer(Options{
@@ -170,6 +337,214 @@ func Test_Render_Layout(t *testing.T) {
 	expect(t, res.Body.String(), "head\n<h1>jeremy</h1>\n\nfoot\n")
 }
 
+type Dashboard struct {
+	Title string
+	User  string
+}
+
+func Test_Render_Layout_Sections(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/sections",
+		Layout:    "layout",
+	}))
+
+	// routing
+	m.Get("/dashboard", func(r Render) {
+		r.HTML(200, "dashboard", Dashboard{"Dashboard", "jeremy"})
+	})
+	m.Get("/plain", func(r Render) {
+		r.HTML(200, "plain", Dashboard{"Plain", "jeremy"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/dashboard", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<html>\n<head>\n<title>Martini</title>\n<link rel=\"stylesheet\" href=\"/dashboard.css\">\n</head>\n<body>\n<h1>Dashboard</h1>\n<p>Signed in as jeremy</p>\n\n</body>\n</html>\n")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/plain", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<html>\n<head>\n<title>Martini</title>\n</head>\n<body>\n<h1>Plain</h1>\n</body>\n</html>\n")
+}
+
+func Test_Render_Nested_Layouts(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/basic",
+		Layouts:   []string{"admin/layout", "layout"},
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "content", "jeremy")
+	})
+	m.Get("/override", func(r Render) {
+		r.HTML(200, "content", "jeremy", HTMLOptions{
+			Layouts: []string{"admin/layout", "another_layout"},
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "head\nadmin\n<h1>jeremy</h1>\n\n/admin\n\nfoot\n")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/override", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "another head\nadmin\n<h1>jeremy</h1>\n\n/admin\n\nanother foot\n")
+}
+
+func Test_Render_Nested_Layout_Sections(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/sections",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "plain", Dashboard{"Plain", "jeremy"}, HTMLOptions{
+			Layouts: []string{"admin", "layout"},
+		})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<html>\n<head>\n<title>Martini</title>\n</head>\n<body>\n<div class=\"admin\">\n<h1>Plain</h1>\n</div>\n<script src=\"/admin.js\"></script>\n</body>\n</html>\n")
+}
+
+func Test_Render_Partial(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/sections",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "dashboard", Dashboard{"Dashboard", "<jeremy>"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Dashboard</h1>\n<p>Signed in as &lt;jeremy&gt;</p>\n\n")
+}
+
+func Test_Render_Helper_Funcs(t *testing.T) {
+	// the helpers of the application take precedence over ours, but yield
+	tests := []struct {
+		helper   string
+		template string
+		expected string
+	}{
+		{"partial", `{{ partial "sidebar" }}`, "app sidebar"},
+		{"cache", `{{ cache "nav" }}`, "app nav"},
+		{"global", `{{ global "site" }}`, "app site"},
+		{"urlfor", `{{ urlfor "home" }}`, "app home"},
+	}
+
+	for _, test := range tests {
+		m := martini.Classic()
+		m.Use(Renderer(Options{
+			Layout: "layout",
+			Bundle: Bundle{
+				"layout.tmpl": "{{ yield }}",
+				"page.tmpl":   test.template,
+			},
+			Funcs: []template.FuncMap{
+				{
+					test.helper: func(name string) string {
+						return "app " + name
+					},
+				},
+			},
+		}))
+
+		// routing
+		m.Get("/foobar", func(r Render) {
+			r.HTML(200, "page", nil)
+		})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar", nil)
+
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		expect(t, res.Body.String(), test.expected)
+	}
+}
+
+func Test_Render_FileSystem(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		FileSystem: http.Dir("fixtures"),
+		Directory:  "basic",
+		Layout:     "layout",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "admin/index", "jeremy")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "head\n<h1>Admin jeremy</h1>\n\nfoot\n")
+}
+
+func Test_Render_FileSystem_Root(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		FileSystem: http.Dir("fixtures/custom_funcs"),
+		Funcs: []template.FuncMap{
+			{
+				"myCustomFunc": func() string {
+					return "My custom function"
+				},
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "index", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "My custom function\n")
+}
+
 func Test_Render_Nested_HTML(t *testing.T) {
 	m := martini.Classic()
 	m.Use(Renderer(Options{
@@ -213,16 +588,344 @@ func Test_Render_Delimiters(t *testing.T) {
 	expect(t, res.Body.String(), "<h1>Hello jeremy</h1>")
 }
 
+func Test_Render_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "hello.tmpl")
+	writeTemplate(t, file, "<h1>Hello {{.}}</h1>", time.Now().Add(-time.Hour))
+
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: dir,
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "hello", "jeremy")
+	})
+
+	get := func() *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foobar", nil)
+		m.ServeHTTP(res, req)
+		return res
+	}
+
+	res := get()
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Hello jeremy</h1>")
+
+	writeTemplate(t, file, "<h1>Goodbye {{.}}</h1>", time.Now())
+	res = get()
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Goodbye jeremy</h1>")
+
+	// parse errors are shown instead of panicking
+	writeTemplate(t, file, "<h1>Goodbye {{.}</h1>", time.Now().Add(time.Hour))
+	res = get()
+	expect(t, res.Code, 500)
+	expect(t, strings.HasPrefix(res.Body.String(), "template: hello:1: "), true)
+
+	writeTemplate(t, file, "<h1>Hello again {{.}}</h1>", time.Now().Add(2*time.Hour))
+	res = get()
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Hello again jeremy</h1>")
+}
+
+func Test_Render_Reload_Concurrent(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/basic",
+		Layout:    "layout",
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "content", "jeremy")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/foobar", nil)
+			m.ServeHTTP(res, req)
+			expect(t, res.Body.String(), "head\n<h1>jeremy</h1>\n\nfoot\n")
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_Render_Bad_Template_Production(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTemplate(t, filepath.Join(dir, "bad.tmpl"), "{{ end }}", time.Now())
+
+	defer func(env string) { martini.Env = env }(martini.Env)
+	martini.Env = martini.Prod
+
+	defer func() {
+		refute(t, recover(), nil)
+	}()
+	Renderer(Options{
+		Directory: dir,
+	})
+}
+
+func Test_Render_Directories(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directories: []string{"fixtures/roots/base", "fixtures/roots/override"},
+	}))
+
+	// routing
+	m.Get("/hello", func(r Render) {
+		r.HTML(200, "hello", "jeremy")
+	})
+	m.Get("/goodbye", func(r Render) {
+		r.HTML(200, "goodbye", "jeremy")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	m.ServeHTTP(res, req)
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Hi jeremy</h1>\n")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/goodbye", nil)
+	m.ServeHTTP(res, req)
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<p>Goodbye jeremy</p>\n")
+}
+
+func Test_Render_Namespaces(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:  "fixtures/roots/base",
+		Namespaces: map[string]string{"mail": "fixtures/roots/mail"},
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		r.HTML(200, "mail:welcome", "jeremy")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "Welcome jeremy!\n")
+}
+
+func Test_Render_Themes(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/roots/base",
+		Themes:    map[string]string{"acme": "fixtures/roots/themes/acme"},
+		ThemeSelector: func(req *http.Request) string {
+			return req.Header.Get("X-Theme")
+		},
+	}))
+
+	// routing
+	m.Get("/hello", func(r Render) {
+		r.HTML(200, "hello", "jeremy")
+	})
+	m.Get("/goodbye", func(r Render) {
+		r.HTML(200, "goodbye", "jeremy")
+	})
+
+	bodies := []struct {
+		theme string
+		path  string
+		body  string
+	}{
+		{"acme", "/hello", "<h1>Acme welcomes jeremy</h1>\n"},
+		{"acme", "/goodbye", "<p>Goodbye jeremy</p>\n"},
+		{"", "/hello", "<h1>Hello jeremy</h1>\n"},
+		{"unknown", "/hello", "<h1>Hello jeremy</h1>\n"},
+	}
+	for _, b := range bodies {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", b.path, nil)
+		req.Header.Set("X-Theme", b.theme)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		expect(t, res.Body.String(), b.body)
+	}
+}
+
+type tenant struct {
+	theme string
+}
+
+func Test_Render_Themes_Later_Services(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/roots/base",
+		Themes:    map[string]string{"acme": "fixtures/roots/themes/acme"},
+		ThemeSelector: func(t *tenant) string {
+			return t.theme
+		},
+	}))
+	// the selector needs a service mapped after Renderer
+	m.Use(func(c martini.Context) {
+		c.Map(&tenant{"acme"})
+	})
+
+	// routing
+	m.Get("/hello", func(r Render) {
+		r.HTML(200, "hello", "jeremy")
+	})
+	m.Get("/json", func(r Render) {
+		r.JSON(200, "jeremy")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "<h1>Acme welcomes jeremy</h1>\n")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/json", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+}
+
+func Test_Render_Bad_ThemeSelector(t *testing.T) {
+	defer func() {
+		refute(t, recover(), nil)
+	}()
+	Renderer(Options{
+		Directory:     "fixtures/roots/base",
+		ThemeSelector: func() {},
+	})
+}
+
+func Test_Render_TextTemplate(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:      "fixtures/text",
+		TextExtensions: []string{".txt.tmpl"},
+		Funcs: []template.FuncMap{
+			{
+				"team": func() string {
+					return "The <Martini> Team"
+				},
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/welcome.txt", func(r Render) {
+		r.TextTemplate(200, "welcome", struct{ Name string }{"jeremy"})
+	})
+	m.Get("/welcome", func(r Render) {
+		r.HTML(200, "welcome", struct{ Name string }{"jeremy & co"})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/welcome.txt", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentText+"; charset=UTF-8")
+	expect(t, res.Body.String(), "Hello jeremy & <friends>\n-- \nThe <Martini> Team\n")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/welcome", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentHTML+"; charset=UTF-8")
+	expect(t, res.Body.String(), "<p>jeremy &amp; co</p>\n")
+}
+
+func Test_Render_TextTemplate_Disabled(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Bundle: Bundle{
+			"robots.txt.tmpl": "Disallow: {{.}}",
+		},
+	}))
+
+	// routing
+	m.Get("/robots.txt", func(r Render) {
+		r.HTML(200, "robots.txt", "/admin&")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/robots.txt", nil)
+	m.ServeHTTP(res, req)
+
+	// without TextExtensions, .txt.tmpl files are HTML templates as before
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "Disallow: /admin&amp;")
+}
+
+func Test_Render_String(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory:      "fixtures/text",
+		TextExtensions: []string{".txt.tmpl"},
+		Funcs: []template.FuncMap{
+			{
+				"team": func() string {
+					return "Team"
+				},
+			},
+		},
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		html, err := r.HTMLString("welcome", struct{ Name string }{"jeremy"})
+		expect(t, err, nil)
+		expect(t, html, "<p>jeremy</p>\n")
+
+		text, err := r.TextString("welcome", struct{ Name string }{"jeremy"})
+		expect(t, err, nil)
+		expect(t, text, "Hello jeremy & <friends>\n-- \nTeam\n")
+
+		_, err = r.TextString("missing", nil)
+		refute(t, err, nil)
+
+		r.Text(200, "sent")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "sent")
+}
+
 func Test_Render_Error404(t *testing.T) {
 	res := httptest.NewRecorder()
-	r := renderer{res, nil, nil, Options{}, ""}
+	r := renderer{ResponseWriter: res}
 	r.Error(404)
 	expect(t, res.Code, 404)
 }
 
 func Test_Render_Error500(t *testing.T) {
 	res := httptest.NewRecorder()
-	r := renderer{res, nil, nil, Options{}, ""}
+	r := renderer{ResponseWriter: res}
 	r.Error(500)
 	expect(t, res.Code, 500)
 }
@@ -235,7 +938,7 @@ func Test_Render_Redirect_Default(t *testing.T) {
 	}
 	res := httptest.NewRecorder()
 
-	r := renderer{res, &req, nil, Options{}, ""}
+	r := renderer{ResponseWriter: res, req: &req}
 	r.Redirect("two")
 
 	expect(t, res.Code, 302)
@@ -250,7 +953,7 @@ func Test_Render_Redirect_Code(t *testing.T) {
 	}
 	res := httptest.NewRecorder()
 
-	r := renderer{res, &req, nil, Options{}, ""}
+	r := renderer{ResponseWriter: res, req: &req}
 	r.Redirect("two", 307)
 
 	expect(t, res.Code, 307)
@@ -326,6 +1029,15 @@ func Test_Render_Override_Layout(t *testing.T) {
 }
 
 /* Test Helpers */
+func writeTemplate(t *testing.T, file, content string, modified time.Time) {
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func expect(t *testing.T, a interface{}, b interface{}) {
 	if a != b {
 		t.Errorf("Expected %v (type %v) - Got %v (type %v)", b, reflect.TypeOf(b), a, reflect.TypeOf(a))
@@ -0,0 +1,269 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/codegangsta/martini"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ContentEventStream = "text/event-stream"
+	defaultHeartbeat   = 15 * time.Second
+)
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID of the event, which the client sends back in the Last-Event-ID header when it reconnects.
+	ID string
+	// Event is the type of the event, which is dispatched to the listeners of that type. The client defaults to "message".
+	Event string
+	// Data of the event. Strings and byte slices are sent as is, over several data fields if they span several lines.
+	// Anything else is sent as JSON.
+	Data interface{}
+	// Retry sets the reconnection delay of the client, unless it is zero.
+	Retry time.Duration
+}
+
+// EventOptions is a struct for specifying configuration options for the render.Events middleware
+type EventOptions struct {
+	// Heartbeat is the interval of the comments sent to keep the connection open through proxies. Default is 15
+	// seconds, and a negative interval sends no heartbeats.
+	Heartbeat time.Duration
+	// Replay returns the events missed by a client reconnecting with a Last-Event-ID header, which are sent before any
+	// other. Defaults to nil.
+	Replay func(lastEventID string) []Event
+}
+
+// EventStream is a service that can be injected into a Martini handler by the render.Events middleware, to send
+// Server-Sent Events. The response only starts with the first event or comment sent, so that a handler can still
+// write an error instead.
+type EventStream interface {
+	// Send writes the given event and flushes it to the client.
+	Send(e Event) error
+	// Comment writes a comment, which clients ignore, and flushes it to the client.
+	Comment(text string) error
+	// Run sends the events received from the given channel until it is closed or the client disconnects.
+	Run(events <-chan Event) error
+	// LastEventID returns the ID of the last event received by a reconnecting client, or "".
+	LastEventID() string
+	// Done returns a channel which is closed when the client disconnects or the handler returns.
+	Done() <-chan struct{}
+}
+
+// Events is a Middleware that maps a render.EventStream service into the Martini handler chain, for the handlers
+// after it. A single variadic render.EventOptions struct can be optionally provided to configure heartbeats and
+// the replay of missed events.
+func Events(options ...EventOptions) martini.Handler {
+	var opt EventOptions
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.Heartbeat == 0 {
+		opt.Heartbeat = defaultHeartbeat
+	}
+
+	return func(res http.ResponseWriter, req *http.Request, c martini.Context) {
+		s := &eventStream{res: res, req: req, opt: opt, done: make(chan struct{})}
+		go s.watch(req.Context().Done())
+
+		c.MapTo(s, (*EventStream)(nil))
+		c.Next()
+		s.stop()
+	}
+}
+
+type eventStream struct {
+	sync.Mutex
+	res     http.ResponseWriter
+	req     *http.Request
+	opt     EventOptions
+	started bool
+	stopped bool
+	done    chan struct{}
+}
+
+func (s *eventStream) Send(e Event) error {
+	buf, err := formatEvent(e)
+	if err != nil {
+		return err
+	}
+	return s.write(buf)
+}
+
+func (s *eventStream) Comment(text string) error {
+	buf := new(bytes.Buffer)
+	for _, line := range splitLines(text) {
+		buf.WriteString(": " + line + "\n")
+	}
+	return s.write(buf.Bytes())
+}
+
+func (s *eventStream) Run(events <-chan Event) error {
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(e); err != nil {
+				return err
+			}
+		case <-s.done:
+			return errClientGone
+		}
+	}
+}
+
+func (s *eventStream) LastEventID() string {
+	return s.req.Header.Get("Last-Event-ID")
+}
+
+func (s *eventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// write sends b to the client and flushes it, starting the stream first if needed.
+func (s *eventStream) write(b []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.stopped {
+		return errClientGone
+	}
+
+	if !s.started {
+		s.started = true
+		if err := s.start(); err != nil {
+			s.stopLocked()
+			return err
+		}
+	}
+	if err := s.flush(b); err != nil {
+		s.stopLocked()
+		return err
+	}
+	return nil
+}
+
+// start writes the headers and the replayed events, and starts the heartbeats. The caller must hold the lock.
+func (s *eventStream) start() error {
+	s.res.Header().Set(ContentType, ContentEventStream)
+	s.res.Header().Set("Cache-Control", "no-cache")
+	// keep nginx from buffering the stream
+	s.res.Header().Set("X-Accel-Buffering", "no")
+	s.res.WriteHeader(http.StatusOK)
+
+	if id := s.LastEventID(); s.opt.Replay != nil && id != "" {
+		for _, e := range s.opt.Replay(id) {
+			buf, err := formatEvent(e)
+			if err != nil {
+				return err
+			}
+			if err := s.flush(buf); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.opt.Heartbeat > 0 {
+		go s.heartbeat()
+	}
+	return nil
+}
+
+func (s *eventStream) flush(b []byte) error {
+	if _, err := s.res.Write(b); err != nil {
+		return err
+	}
+	if flusher, ok := s.res.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// heartbeat sends an empty comment at every interval, until the stream stops.
+func (s *eventStream) heartbeat() {
+	ticker := time.NewTicker(s.opt.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.write([]byte(":\n")) != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// watch stops the stream when the client disconnects.
+func (s *eventStream) watch(closed <-chan struct{}) {
+	select {
+	case <-closed:
+		s.stop()
+	case <-s.done:
+	}
+}
+
+func (s *eventStream) stop() {
+	s.Lock()
+	defer s.Unlock()
+	s.stopLocked()
+}
+
+func (s *eventStream) stopLocked() {
+	if !s.stopped {
+		s.stopped = true
+		close(s.done)
+	}
+}
+
+// formatEvent returns the fields of e in the text/event-stream format, ending with the blank line that dispatches it.
+func formatEvent(e Event) ([]byte, error) {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Event, "\r\n") {
+		return nil, fmt.Errorf("render: event ID and type can't contain line breaks")
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		result, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = string(result)
+	}
+
+	buf := new(bytes.Buffer)
+	if len(e.ID) > 0 {
+		buf.WriteString("id: " + e.ID + "\n")
+	}
+	if len(e.Event) > 0 {
+		buf.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(buf, "retry: %d\n", e.Retry/time.Millisecond)
+	}
+	for _, line := range splitLines(data) {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// splitLines splits s at every line break the text/event-stream format knows of.
+func splitLines(s string) []string {
+	return strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(s), "\n")
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Events(t *testing.T) {
+	m := martini.Classic()
+
+	// routing
+	m.Get("/events", Events(), func(s EventStream) {
+		expect(t, s.Send(Event{ID: "1", Event: "greeting", Data: "hello\nworld", Retry: 3 * time.Second}), nil)
+		expect(t, s.Send(Event{Data: Greeting{"hello", "world"}}), nil)
+		expect(t, s.Comment("still there?"), nil)
+		refute(t, s.Send(Event{ID: "2\n3"}), nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Header().Get(ContentType), ContentEventStream)
+	expect(t, res.Header().Get("Cache-Control"), "no-cache")
+	expect(t, res.Body.String(), "id: 1\nevent: greeting\nretry: 3000\ndata: hello\ndata: world\n\n"+
+		"data: {\"one\":\"hello\",\"two\":\"world\"}\n\n"+
+		": still there?\n")
+	expect(t, res.Flushed, true)
+}
+
+func Test_Events_Error_Before_Stream(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/events", Events(), func(s EventStream, r Render) {
+		r.Error(401)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 401)
+	expect(t, res.Header().Get(ContentType), "")
+}
+
+func Test_Events_Replay(t *testing.T) {
+	m := martini.Classic()
+
+	// routing
+	m.Get("/events", Events(EventOptions{
+		Replay: func(lastEventID string) []Event {
+			expect(t, lastEventID, "41")
+			return []Event{{ID: "42", Data: "missed"}}
+		},
+	}), func(s EventStream) {
+		expect(t, s.LastEventID(), "41")
+
+		events := make(chan Event, 1)
+		events <- Event{ID: "43", Data: "new"}
+		close(events)
+		expect(t, s.Run(events), nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), "id: 42\ndata: missed\n\nid: 43\ndata: new\n\n")
+}
+
+func Test_Events_Heartbeat(t *testing.T) {
+	m := martini.Classic()
+
+	// routing
+	m.Get("/events", Events(EventOptions{
+		Heartbeat: time.Millisecond,
+	}), func(s EventStream) {
+		s.Send(Event{Data: "hello"})
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, strings.HasPrefix(res.Body.String(), "data: hello\n\n:\n"), true)
+}
+
+func Test_Events_Disconnect(t *testing.T) {
+	m := martini.Classic()
+
+	var err error
+	// routing
+	m.Get("/events", Events(), func(s EventStream) {
+		err = s.Run(make(chan Event))
+		_, open := <-s.Done()
+		expect(t, open, false)
+	})
+
+	m.ServeHTTP(httptest.NewRecorder(), disconnectedRequest("GET", "/events"))
+
+	expect(t, err, errClientGone)
+}
@@ -0,0 +1,129 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Iterator yields the elements of a JSON array streamed by JSONStream, one at a time.
+type Iterator interface {
+	// Next advances to the next element, and returns false when there are no more.
+	Next() bool
+	// Value returns the current element.
+	Value() interface{}
+}
+
+// errClientGone stops a stream when the client disconnects.
+var errClientGone = errors.New("client disconnected")
+
+// JSONStream writes the elements received from a channel, or yielded by an Iterator, as a JSON array. Each element is
+// marshaled and flushed to the client in turn, so the whole array is never held in memory. The stream stops when
+// the client disconnects; the sender of a channel should then stop too, since nothing receives from it anymore.
+//
+// If the first element can't be marshaled, a 500 Internal Server Error is written. Past that point the status has
+// been sent, so the stream is cut short instead, leaving an incomplete array.
+func (r *renderer) JSONStream(status int, v interface{}) {
+	var closed <-chan struct{}
+	if r.req != nil {
+		closed = r.req.Context().Done()
+	}
+	next, err := jsonElements(v, closed)
+	if err != nil {
+		r.renderFailed(err, false)
+		return
+	}
+	flusher, _ := r.ResponseWriter.(http.Flusher)
+
+	open, separator, end := "[", ",", "]"
+	if r.opt.IndentJSON {
+		open, separator, end = "[\n  ", ",\n  ", "\n]"
+	}
+
+	count := 0
+	for {
+		elem, ok, err := next()
+		if err != nil {
+			return
+		} else if !ok {
+			break
+		}
+
+		var result []byte
+		if r.opt.IndentJSON {
+			result, err = json.MarshalIndent(elem, "  ", "  ")
+		} else {
+			result, err = json.Marshal(elem)
+		}
+		if err != nil {
+			if count == 0 {
+				r.renderFailed(err, false)
+			}
+			return
+		}
+
+		if count == 0 {
+			r.Header().Set(ContentType, ContentJSON+r.compiledCharset)
+			r.WriteHeader(status)
+			_, err = r.Write([]byte(open))
+		} else {
+			_, err = r.Write([]byte(separator))
+		}
+		if err == nil {
+			_, err = r.Write(result)
+		}
+		if err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		count++
+	}
+
+	if count == 0 {
+		r.Header().Set(ContentType, ContentJSON+r.compiledCharset)
+		r.WriteHeader(status)
+		r.Write([]byte("[]"))
+		return
+	}
+	r.Write([]byte(end))
+}
+
+// jsonElements returns a function yielding the elements of v, which is a channel or an Iterator, one at a time. It
+// returns errClientGone once closed is, and false when there are no more elements.
+func jsonElements(v interface{}, closed <-chan struct{}) (func() (interface{}, bool, error), error) {
+	if it, ok := v.(Iterator); ok {
+		return func() (interface{}, bool, error) {
+			select {
+			case <-closed:
+				return nil, false, errClientGone
+			default:
+			}
+			if !it.Next() {
+				return nil, false, nil
+			}
+			return it.Value(), true, nil
+		}, nil
+	}
+
+	ch := reflect.ValueOf(v)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		return nil, fmt.Errorf("render: JSONStream needs a channel or a render.Iterator, got %T", v)
+	}
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(closed)},
+	}
+	return func() (interface{}, bool, error) {
+		chosen, elem, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return nil, false, errClientGone
+		} else if !ok {
+			return nil, false, nil
+		}
+		return elem.Interface(), true, nil
+	}, nil
+}
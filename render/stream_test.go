@@ -0,0 +1,151 @@
+package render
+
+import (
+	"context"
+	"github.com/codegangsta/martini"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type countdown struct {
+	n int
+}
+
+func (c *countdown) Next() bool {
+	c.n--
+	return c.n >= 0
+}
+
+func (c *countdown) Value() interface{} {
+	return c.n
+}
+
+// disconnectedRequest returns a request whose client has already disconnected.
+func disconnectedRequest(method, path string) *http.Request {
+	req, _ := http.NewRequest(method, path, nil)
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	return req.WithContext(ctx)
+}
+
+func Test_Render_JSONStream(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/channel", func(r Render) {
+		ch := make(chan Greeting)
+		go func() {
+			ch <- Greeting{"hello", "world"}
+			ch <- Greeting{"hola", "mundo"}
+			close(ch)
+		}()
+		r.JSONStream(200, ch)
+	})
+	m.Get("/iterator", func(r Render) {
+		r.JSONStream(200, &countdown{3})
+	})
+	m.Get("/empty", func(r Render) {
+		ch := make(chan int)
+		close(ch)
+		r.JSONStream(200, ch)
+	})
+
+	bodies := map[string]string{
+		"/channel":  `[{"one":"hello","two":"world"},{"one":"hola","two":"mundo"}]`,
+		"/iterator": `[2,1,0]`,
+		"/empty":    `[]`,
+	}
+	for path, body := range bodies {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, 200)
+		expect(t, res.Header().Get(ContentType), ContentJSON+"; charset=UTF-8")
+		expect(t, res.Body.String(), body)
+		expect(t, res.Flushed, path != "/empty")
+	}
+}
+
+func Test_Render_JSONStream_Indented(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		IndentJSON: true,
+	}))
+
+	// routing
+	m.Get("/foobar", func(r Render) {
+		ch := make(chan Greeting, 2)
+		ch <- Greeting{"hello", "world"}
+		ch <- Greeting{"hola", "mundo"}
+		close(ch)
+		r.JSONStream(200, ch)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foobar", nil)
+	m.ServeHTTP(res, req)
+
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), `[
+  {
+    "one": "hello",
+    "two": "world"
+  },
+  {
+    "one": "hola",
+    "two": "mundo"
+  }
+]`)
+}
+
+func Test_Render_JSONStream_Errors(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer())
+
+	// routing
+	m.Get("/invalid", func(r Render) {
+		r.JSONStream(200, []int{1, 2})
+	})
+	m.Get("/first", func(r Render) {
+		ch := make(chan float64, 1)
+		ch <- math.NaN()
+		close(ch)
+		r.JSONStream(200, ch)
+	})
+	m.Get("/second", func(r Render) {
+		ch := make(chan float64, 2)
+		ch <- 1
+		ch <- math.NaN()
+		close(ch)
+		r.JSONStream(200, ch)
+	})
+
+	codes := map[string]int{
+		"/invalid": 500,
+		"/first":   500,
+		"/second":  200,
+	}
+	for path, code := range codes {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(res, req)
+
+		expect(t, res.Code, code)
+	}
+}
+
+func Test_Render_JSONStream_Disconnect(t *testing.T) {
+	res := httptest.NewRecorder()
+	r := renderer{ResponseWriter: res, req: disconnectedRequest("GET", "/")}
+
+	// nothing is ever sent, so only the disconnection ends the stream
+	r.JSONStream(200, make(chan int))
+	expect(t, res.Body.String(), "")
+
+	r.JSONStream(200, &countdown{3})
+	expect(t, res.Body.String(), "")
+}
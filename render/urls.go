@@ -0,0 +1,126 @@
+package render
+
+import (
+	"fmt"
+	"github.com/codegangsta/martini"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// routeParam matches the parameters of a martini route pattern: named parameters, globs and regexp groups
+var routeParam = regexp.MustCompile(`:[^/#?()\.\\]+|\*\*|\(\?P<([a-zA-Z0-9_]+)>[^)]*\)`)
+
+// URLFor returns the URL of the route with the given name, or pattern when no route has that name. Params are
+// name/value pairs: each fills the route parameter of that name, as in ":id" or "(?P<id>[0-9]+)", and the others
+// are added to the query string, skipping nil values. Globs are filled by "_1", "_2" and so on, like in
+// martini.Params. Unlike martini.Routes.URLFor, an unknown route or a missing parameter is an error.
+//
+//	URLFor(routes, "user_edit", "id", 42, "tab", "profile") // "/users/42/edit?tab=profile"
+func URLFor(routes martini.Routes, name string, params ...interface{}) (string, error) {
+	route := findRoute(routes, name)
+	if route == nil {
+		return "", fmt.Errorf("render: no route named %q", name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("render: URL params for route %q must be name/value pairs", name)
+	}
+	values := map[string]interface{}{}
+	var order []string
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("render: URL param names for route %q must be strings, got %T", name, params[i])
+		}
+		if _, ok := values[key]; !ok {
+			order = append(order, key)
+		}
+		values[key] = params[i+1]
+	}
+
+	var err error
+	used := map[string]bool{}
+	globs := 0
+	path := routeParam.ReplaceAllStringFunc(route.Pattern(), func(m string) string {
+		var key string
+		switch {
+		case m == "**":
+			globs++
+			key = fmt.Sprintf("_%d", globs)
+		case m[0] == ':':
+			key = m[1:]
+		default:
+			key = routeParam.FindStringSubmatch(m)[1]
+		}
+
+		val, ok := values[key]
+		if !ok || val == nil {
+			if err == nil {
+				err = fmt.Errorf("render: missing URL param %q for route %q", key, name)
+			}
+			return m
+		}
+		used[key] = true
+
+		if m == "**" {
+			// globs span several segments, so their slashes are kept
+			segments := strings.Split(fmt.Sprint(val), "/")
+			for i, s := range segments {
+				segments[i] = escapeSegment(s)
+			}
+			return strings.Join(segments, "/")
+		}
+		return escapeSegment(fmt.Sprint(val))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	for _, key := range order {
+		if val := values[key]; !used[key] && val != nil {
+			query.Add(key, fmt.Sprint(val))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// findRoute returns the route with the given name, falling back to the one with the given pattern.
+func findRoute(routes martini.Routes, name string) martini.Route {
+	if routes == nil {
+		return nil
+	}
+
+	all := routes.All()
+	for _, route := range all {
+		if route.GetName() == name {
+			return route
+		}
+	}
+	for _, route := range all {
+		if route.Pattern() == name {
+			return route
+		}
+	}
+	return nil
+}
+
+// escapeSegment escapes s for use as a single path segment.
+func escapeSegment(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// urlFor lets templates build the URL of a route with {{ urlfor "name" "param" value }}, using the martini.Routes
+// service of the request.
+func (r *renderer) urlFor(name string, params ...interface{}) (string, error) {
+	v := r.context.Get(reflect.TypeOf((*martini.Routes)(nil)).Elem())
+	if !v.IsValid() || v.IsNil() {
+		return "", fmt.Errorf("urlfor needs martini.Routes to be mapped, as martini.Classic does")
+	}
+	return URLFor(v.Interface().(martini.Routes), name, params...)
+}
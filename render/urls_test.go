@@ -0,0 +1,83 @@
+package render
+
+import (
+	"github.com/codegangsta/martini"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func urlRoutes() martini.Router {
+	r := martini.NewRouter()
+	r.Get("/users/:id/edit", func() {}).Name("user_edit")
+	r.Get("/posts/(?P<year>[0-9]{4})/:slug", func() {}).Name("post")
+	r.Get("/files/**", func() {})
+	return r
+}
+
+func Test_URLFor(t *testing.T) {
+	routes := urlRoutes().(martini.Routes)
+
+	u, err := URLFor(routes, "user_edit", "id", 42)
+	expect(t, err, nil)
+	expect(t, u, "/users/42/edit")
+
+	u, err = URLFor(routes, "user_edit", "id", "a b/c", "tab", "profile", "q", "x&y", "page", nil)
+	expect(t, err, nil)
+	expect(t, u, "/users/a%20b%2Fc/edit?q=x%26y&tab=profile")
+
+	u, err = URLFor(routes, "post", "year", 2014, "slug", "hello")
+	expect(t, err, nil)
+	expect(t, u, "/posts/2014/hello")
+
+	// routes without a name are found by their pattern
+	u, err = URLFor(routes, "/files/**", "_1", "docs/read me.txt")
+	expect(t, err, nil)
+	expect(t, u, "/files/docs/read%20me.txt")
+}
+
+func Test_URLFor_Errors(t *testing.T) {
+	routes := urlRoutes().(martini.Routes)
+
+	_, err := URLFor(routes, "user_edit")
+	expect(t, err.Error(), `render: missing URL param "id" for route "user_edit"`)
+
+	_, err = URLFor(routes, "user_edit", "id", nil)
+	refute(t, err, nil)
+
+	_, err = URLFor(routes, "user_edit", "id")
+	refute(t, err, nil)
+
+	_, err = URLFor(routes, "user_edit", 1, 2)
+	refute(t, err, nil)
+
+	_, err = URLFor(routes, "nope")
+	expect(t, err.Error(), `render: no route named "nope"`)
+}
+
+func Test_Render_URLFor(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Renderer(Options{
+		Directory: "fixtures/urls",
+	}))
+
+	// routing
+	m.Get("/users/:id/edit", func() {}).Name("user_edit")
+	m.Get("/link", func(r Render) {
+		r.HTML(200, "link", struct{ ID int }{7})
+	})
+	m.Get("/missing", func(r Render) {
+		r.HTML(200, "missing", nil)
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/link", nil)
+	m.ServeHTTP(res, req)
+	expect(t, res.Code, 200)
+	expect(t, res.Body.String(), `<a href="/users/7/edit?tab=profile">Edit</a>`)
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/missing", nil)
+	m.ServeHTTP(res, req)
+	expect(t, res.Code, 500)
+}